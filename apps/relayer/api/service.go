@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/relay"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/address"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// Relayer is the running relayer the API reports and controls.
+type Relayer interface {
+	Status() relay.Status
+	Pause()
+	Resume()
+	RerelayBlock(index uint32) error
+	RerelayRequest(id uint64) error
+}
+
+// Deposits is the index of deposits seen by the relayer.
+type Deposits interface {
+	Deposit(id uint64) (*store.Deposit, error)
+	DepositsBySender(from util.Uint160) ([]store.Deposit, error)
+	DepositsByRecipient(to common.Address) ([]store.Deposit, error)
+}
+
+// Service serves relayer status and admin endpoints, requests must bear the
+// configured token:
+//
+//	GET  /status                relayer status
+//	POST /pause                 stop relaying before the next block
+//	POST /resume                continue relaying
+//	POST /rerelay?block=<index> relay a block again
+//	POST /rerelay?request=<id>  relay a deposit request again
+//
+// Deposits are public on chain, so they're queried without token:
+//
+//	GET /deposits/<id>             deposit of request id
+//	GET /deposits?from=<address>   deposits of Neo sender, address or script hash
+//	GET /deposits?to=<address>     deposits to EVM recipient
+type Service struct {
+	server *http.Server
+}
+
+func NewService(address string, token string, relayer Relayer, deposits Deposits) *Service {
+	return &Service{
+		server: &http.Server{
+			Addr:    address,
+			Handler: newHandler(token, relayer, deposits),
+		},
+	}
+}
+
+// Start serves API in background.
+func (s *Service) Start() {
+	log.Printf("serving api, address=%s\n", s.server.Addr)
+	go func() {
+		err := s.server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("api service stopped: %s\n", err)
+		}
+	}()
+}
+
+func (s *Service) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func newHandler(token string, relayer Relayer, deposits Deposits) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", handle(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, relayer.Status())
+	}))
+	mux.HandleFunc("/pause", handle(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		relayer.Pause()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("/resume", handle(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		relayer.Resume()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux.HandleFunc("/rerelay", handle(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		query := r.URL.Query()
+		switch {
+		case query.Has("block"):
+			var index uint64
+			index, err = strconv.ParseUint(query.Get("block"), 10, 32)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, errors.New("invalid block index"))
+				return
+			}
+			err = relayer.RerelayBlock(uint32(index))
+		case query.Has("request"):
+			var id uint64
+			id, err = strconv.ParseUint(query.Get("request"), 10, 64)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, errors.New("invalid request id"))
+				return
+			}
+			err = relayer.RerelayRequest(id)
+		default:
+			writeError(w, http.StatusBadRequest, errors.New("missing block or request"))
+			return
+		}
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusAccepted)
+		case errors.Is(err, relay.ErrNotRelayed):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, relay.ErrUnknownRequest):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, relay.ErrBusy):
+			writeError(w, http.StatusServiceUnavailable, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+	}))
+	admin := authorize(token, mux)
+	public := http.NewServeMux()
+	public.HandleFunc("/deposits", handle(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		var (
+			ds  []store.Deposit
+			err error
+		)
+		query := r.URL.Query()
+		switch {
+		case query.Has("from"):
+			from, e := parseSender(query.Get("from"))
+			if e != nil {
+				writeError(w, http.StatusBadRequest, errors.New("invalid sender"))
+				return
+			}
+			ds, err = deposits.DepositsBySender(from)
+		case query.Has("to"):
+			to := query.Get("to")
+			if !common.IsHexAddress(to) {
+				writeError(w, http.StatusBadRequest, errors.New("invalid recipient"))
+				return
+			}
+			ds, err = deposits.DepositsByRecipient(common.HexToAddress(to))
+		default:
+			writeError(w, http.StatusBadRequest, errors.New("missing from or to"))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, ds)
+	}))
+	public.HandleFunc("/deposits/", handle(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/deposits/"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid request id"))
+			return
+		}
+		d, err := deposits.Deposit(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if d == nil {
+			writeError(w, http.StatusNotFound, errors.New("deposit not found"))
+			return
+		}
+		writeJSON(w, http.StatusOK, d)
+	}))
+	public.Handle("/", admin)
+	return public
+}
+
+// parseSender parses Neo address or script hash.
+func parseSender(s string) (util.Uint160, error) {
+	if h, err := address.StringToUint160(s); err == nil {
+		return h, nil
+	}
+	return util.Uint160DecodeStringLE(strings.TrimPrefix(s, "0x"))
+}
+
+// authorize passes requests bearing token to next.
+func authorize(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		bearer := strings.TrimPrefix(auth, "Bearer ")
+		if bearer == auth || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handle serves requests of method with h.
+func handle(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("can't write api response: %s\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/relay"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/address"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRelayer struct {
+	paused  bool
+	block   uint32
+	request uint64
+}
+
+func (r *testRelayer) Status() relay.Status {
+	return relay.Status{Index: 10, Best: true, Paused: r.paused}
+}
+
+func (r *testRelayer) Pause() { r.paused = true }
+
+func (r *testRelayer) Resume() { r.paused = false }
+
+func (r *testRelayer) RerelayBlock(index uint32) error {
+	if index >= 10 {
+		return relay.ErrNotRelayed
+	}
+	r.block = index
+	return nil
+}
+
+func (r *testRelayer) RerelayRequest(id uint64) error {
+	if id == 0 {
+		return relay.ErrUnknownRequest
+	}
+	r.request = id
+	return nil
+}
+
+// newTestHandler returns function serving requests bearing token with
+// handler of relayer and deposits of db.
+func newTestHandler(t *testing.T, relayer Relayer) (func(method, url, token string) *httptest.ResponseRecorder, *store.Store) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	h := newHandler("secret", relayer, db)
+	return func(method, url, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}, db
+}
+
+func TestService(t *testing.T) {
+	relayer := &testRelayer{}
+	do, _ := newTestHandler(t, relayer)
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/status", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/status", "wrong").Code)
+	w := do(http.MethodGet, "/status", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status relay.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, uint32(10), status.Index)
+	assert.True(t, status.Best)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/pause", "secret").Code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/pause", "secret").Code)
+	assert.True(t, relayer.paused)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/resume", "secret").Code)
+	assert.False(t, relayer.paused)
+
+	assert.Equal(t, http.StatusAccepted, do(http.MethodPost, "/rerelay?block=5", "secret").Code)
+	assert.Equal(t, uint32(5), relayer.block)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/rerelay?block=10", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/rerelay?block=x", "secret").Code)
+	assert.Equal(t, http.StatusAccepted, do(http.MethodPost, "/rerelay?request=7", "secret").Code)
+	assert.Equal(t, uint64(7), relayer.request)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/rerelay?request=0", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/rerelay", "secret").Code)
+}
+
+func TestDeposits(t *testing.T) {
+	do, db := newTestHandler(t, &testRelayer{})
+	get := func(url string) *httptest.ResponseRecorder {
+		return do(http.MethodGet, url, "")
+	}
+	from := util.Uint160{1, 2, 3}
+	to := common.Address{4, 5, 6}
+	require.NoError(t, db.PutDeposit(&store.Deposit{RequestId: 7, From: from, To: to, Amount: 100, Status: store.DepositMinted}))
+
+	w := get("/deposits/7")
+	require.Equal(t, http.StatusOK, w.Code)
+	var d store.Deposit
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.Equal(t, uint64(7), d.RequestId)
+	assert.Equal(t, store.DepositMinted, d.Status)
+	assert.Equal(t, http.StatusNotFound, get("/deposits/8").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/deposits/x").Code)
+
+	for _, query := range []string{
+		"from=" + address.Uint160ToString(from),
+		"from=0x" + from.StringLE(),
+		"to=" + to.Hex(),
+	} {
+		w = get("/deposits?" + query)
+		require.Equal(t, http.StatusOK, w.Code, query)
+		var ds []store.Deposit
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ds))
+		require.Equal(t, 1, len(ds), query)
+		assert.Equal(t, uint64(7), ds[0].RequestId)
+	}
+	w = get("/deposits?to=" + common.Address{1}.Hex())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[]\n", w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, get("/deposits?from=x").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/deposits").Code)
+	// admin endpoints still need token
+	assert.Equal(t, http.StatusUnauthorized, get("/status").Code)
+}
@@ -3,21 +3,81 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/big"
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/nspcc-dev/neo-go/pkg/util"
 )
 
+const (
+	DefaultDB           = "relayer.db"
+	DefaultGasPriceBump = 10
+	DefaultReplaceAfter = 3
+
+	DefaultPrefetchWorkers = 4
+	DefaultPrefetchWindow  = 16
+
+	DefaultSeedCheckInterval = 30
+	DefaultMaxSeedLag        = 3
+
+	DefaultShutdownTimeout = 60
+
+	LegacyTxType     = "legacy"
+	DynamicFeeTxType = "dynamic"
+	// AutoTxType uses dynamic fee transactions once side chain blocks have
+	// base fee.
+	AutoTxType = "auto"
+)
+
 type Config struct {
-	MainSeeds         []string       `json:"mainSeeds"`
-	SideSeeds         []string       `json:"sideSeeds"`
-	VerifiedRootStart uint32         `json:"verifiedRootStart"`
-	Start             uint32         `json:"start"`
-	End               uint32         `json:"end"`
-	BridgeContract    util.Uint160   `json:"bridgeContract"`
-	Wallet            string         `json:"wallet"`
-	Relayer           common.Address `json:"relayer"`
+	MainSeeds            []string       `json:"mainSeeds"`
+	SideSeeds            []string       `json:"sideSeeds"`
+	VerifiedRootStart    uint32         `json:"verifiedRootStart"`
+	Start                uint32         `json:"start"`
+	End                  uint32         `json:"end"`
+	BridgeContract       util.Uint160   `json:"bridgeContract"`
+	Wallet               string         `json:"wallet"`
+	Relayer              common.Address `json:"relayer"`
+	DB                   string         `json:"db"`
+	SideStart            uint32         `json:"sideStart"`
+	SideEnd              uint32         `json:"sideEnd"`
+	NeoWallet            string         `json:"neoWallet"`
+	NeoRelayer           string         `json:"neoRelayer"`
+	Confirmations        uint32         `json:"confirmations"`
+	MaxGasPrice          *big.Int       `json:"maxGasPrice"`
+	GasPriceBump         uint32         `json:"gasPriceBump"`
+	ReplaceAfter         uint32         `json:"replaceAfter"`
+	TxType               string         `json:"txType"`
+	MaxFeePerGas         *big.Int       `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *big.Int       `json:"maxPriorityFeePerGas"`
+	MetricsAddress       string         `json:"metricsAddress"`
+	PrefetchWorkers      int            `json:"prefetchWorkers"`
+	PrefetchWindow       uint32         `json:"prefetchWindow"`
+	// StrictScan fetches application logs of all transactions instead of
+	// bridge and RoleManagement calls only, e.g. for audits.
+	StrictScan bool `json:"strictScan"`
+	// MainWSSeeds are main chain WebSocket endpoints, e.g.
+	// ws://localhost:10452/ws, new blocks are polled only if empty.
+	MainWSSeeds []string `json:"mainWSSeeds"`
+	// SeedCheckInterval is in seconds, seeds lagging more than MaxSeedLag
+	// blocks behind the highest one are not used.
+	SeedCheckInterval uint32 `json:"seedCheckInterval"`
+	MaxSeedLag        uint32 `json:"maxSeedLag"`
+	// QuorumSize main seeds are asked for application logs and state roots
+	// deciding mints and QuorumThreshold of them must match, quorum reads
+	// are disabled if QuorumSize is zero.
+	QuorumSize      int `json:"quorumSize"`
+	QuorumThreshold int `json:"quorumThreshold"`
+	// ShutdownTimeout is in seconds, on SIGINT or SIGTERM transactions of
+	// the block in progress are waited for that long before exit. Grace
+	// period of the deployment should be longer.
+	ShutdownTimeout uint32 `json:"shutdownTimeout"`
+	// APIAddress serves relayer status and admin endpoints if set, requests
+	// must bear APIToken.
+	APIAddress string `json:"apiAddress"`
+	APIToken   string `json:"apiToken"`
 }
 
 func Load(path string) (*Config, error) {
@@ -47,5 +107,52 @@ func (cfg *Config) check() error {
 	if cfg.BridgeContract == (util.Uint160{}) {
 		return errors.New("invalid manage contract address")
 	}
+	if cfg.NeoWallet != "" && cfg.NeoRelayer == "" {
+		return errors.New("missing neo relayer")
+	}
+	if cfg.APIAddress != "" && cfg.APIToken == "" {
+		return errors.New("missing api token")
+	}
+	switch cfg.TxType {
+	case "", LegacyTxType, DynamicFeeTxType, AutoTxType:
+	default:
+		return fmt.Errorf("unknown tx type %s", cfg.TxType)
+	}
+	if cfg.DB == "" {
+		cfg.DB = DefaultDB
+	}
+	if cfg.GasPriceBump == 0 {
+		cfg.GasPriceBump = DefaultGasPriceBump
+	}
+	if cfg.ReplaceAfter == 0 {
+		cfg.ReplaceAfter = DefaultReplaceAfter
+	}
+	if cfg.PrefetchWorkers < 0 {
+		return errors.New("negative prefetch workers")
+	}
+	if cfg.PrefetchWorkers == 0 {
+		cfg.PrefetchWorkers = DefaultPrefetchWorkers
+	}
+	if cfg.PrefetchWindow == 0 {
+		cfg.PrefetchWindow = DefaultPrefetchWindow
+	}
+	if cfg.SeedCheckInterval == 0 {
+		cfg.SeedCheckInterval = DefaultSeedCheckInterval
+	}
+	if cfg.MaxSeedLag == 0 {
+		cfg.MaxSeedLag = DefaultMaxSeedLag
+	}
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if cfg.QuorumSize < 0 || cfg.QuorumSize > len(cfg.MainSeeds) {
+		return fmt.Errorf("quorum size %d out of main seeds", cfg.QuorumSize)
+	}
+	if cfg.QuorumSize > 0 && cfg.QuorumThreshold == 0 {
+		cfg.QuorumThreshold = cfg.QuorumSize/2 + 1
+	}
+	if cfg.QuorumThreshold < 0 || cfg.QuorumThreshold > cfg.QuorumSize {
+		return fmt.Errorf("quorum threshold %d out of quorum size %d", cfg.QuorumThreshold, cfg.QuorumSize)
+	}
 	return nil
 }
@@ -0,0 +1,64 @@
+package constantclient
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/actor"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+)
+
+var _ actor.RPCActor = (*rpcActor)(nil)
+
+// rpcActor adapts main chain calls to actor.RPCActor of neo-go which doesn't
+// take contexts, calls are made with ctx.
+type rpcActor struct {
+	c   *ConstantClient
+	ctx context.Context
+}
+
+// RPCActor returns main chain client for neo-go actors and invokers, their
+// calls are made with ctx.
+func (c *ConstantClient) RPCActor(ctx context.Context) actor.RPCActor {
+	return &rpcActor{c: c, ctx: ctx}
+}
+
+func (a *rpcActor) GetBlockCount() (uint32, error) {
+	return a.c.GetBlockCount(a.ctx)
+}
+
+func (a *rpcActor) GetVersion() (*result.Version, error) {
+	return a.c.GetVersion(a.ctx)
+}
+
+func (a *rpcActor) InvokeScript(script []byte, signers []transaction.Signer) (*result.Invoke, error) {
+	return a.c.InvokeScript(a.ctx, script, signers)
+}
+
+func (a *rpcActor) InvokeFunction(contract util.Uint160, operation string, params []smartcontract.Parameter, signers []transaction.Signer) (*result.Invoke, error) {
+	return a.c.InvokeFunction(a.ctx, contract, operation, params, signers)
+}
+
+func (a *rpcActor) InvokeContractVerify(contract util.Uint160, params []smartcontract.Parameter, signers []transaction.Signer, witnesses ...transaction.Witness) (*result.Invoke, error) {
+	return a.c.InvokeContractVerify(a.ctx, contract, params, signers, witnesses...)
+}
+
+func (a *rpcActor) TerminateSession(sessionID uuid.UUID) (bool, error) {
+	return a.c.TerminateSession(a.ctx, sessionID)
+}
+
+func (a *rpcActor) TraverseIterator(sessionID, iteratorID uuid.UUID, maxItemsCount int) ([]stackitem.Item, error) {
+	return a.c.TraverseIterator(a.ctx, sessionID, iteratorID, maxItemsCount)
+}
+
+func (a *rpcActor) CalculateNetworkFee(tx *transaction.Transaction) (int64, error) {
+	return a.c.CalculateNetworkFee(a.ctx, tx)
+}
+
+func (a *rpcActor) SendRawTransaction(tx *transaction.Transaction) (util.Uint256, error) {
+	return a.c.SendRawTransaction(a.ctx, tx)
+}
@@ -1,125 +1,295 @@
 package constantclient
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"net/http"
+	"sync"
+	"time"
 
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/client"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/request"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
 	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/native/noderoles"
 	mstate "github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/hash"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
 	mio "github.com/nspcc-dev/neo-go/pkg/io"
 	"github.com/nspcc-dev/neo-go/pkg/neorpc"
 	mresult "github.com/nspcc-dev/neo-go/pkg/neorpc/result"
 	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/invoker"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/rolemgmt"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
 	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
 )
 
+const (
+	sideRequestTimeout = 4 * time.Second
+	// wsBufferSize is the capacity of subscription receivers, WSClient
+	// blocks reading the socket when they are full.
+	wsBufferSize = 16
+)
+
+// ErrNoAvailableSeed means none of the seeds can be connected.
+var ErrNoAvailableSeed = errors.New("no available seed")
+
+// ErrNoQuorum means not enough seeds return the same result for a quorum
+// read.
+var ErrNoQuorum = errors.New("no quorum")
+
+// ErrNoWSSeed means no main chain WebSocket seed is configured.
+var ErrNoWSSeed = errors.New("no WebSocket seed")
+
+// ConstantClient is a failover client of main and side chains. Every call
+// takes a context and is bounded by callTimeout.
 type ConstantClient struct {
-	mainSeeds []string
-	sideSeeds []string
-	mIndex    int
-	sIndex    int
-	mClient   *rpcclient.Client
-	sClient   *client.Client
+	// main and side are failover states of main and side seeds, requests
+	// are routed to the best healthy seed.
+	main *failover
+	side *failover
+	// wsSeeds are main chain WebSocket endpoints for subscriptions
+	wsSeeds []string
+	// mtx guards wsIndex, subscriptions are rotated round-robin
+	mtx     sync.Mutex
+	wsIndex int
+	// health makes the health check started once per client
+	health sync.Once
+	// ctx is canceled by Close, requests in progress are aborted
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// sideClient is side chain client of seed.
+type sideClient struct {
+	*client.Client
+	seed string
 }
 
-func New(mseeds, sseeds []string) *ConstantClient {
+func New(mseeds, sseeds, wsseeds []string) *ConstantClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	c := &ConstantClient{
-		mainSeeds: mseeds,
-		sideSeeds: sseeds,
-		mIndex:    0,
-		sIndex:    0,
-		mClient:   nil,
-		sClient:   nil,
-	}
-	c.ensureNewClient(true)
-	c.ensureNewClient(false)
+		main:    newFailover(ctx, "main", mseeds, newMainSeedPool(ctx, mseeds), dialMain, isMainNetworkError),
+		side:    newFailover(ctx, "side", sseeds, newSideSeedPool(ctx, sseeds), dialSide, isSideNetworkError),
+		wsSeeds: wsseeds,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	// clients unavailable now are dialed on the first request
+	if _, _, err := c.main.get(ctx, make(map[int]bool)); err != nil {
+		log.Printf("can't initialize main client: %s\n", err)
+	}
+	if _, _, err := c.side.get(ctx, make(map[int]bool)); err != nil {
+		log.Printf("can't initialize side client: %s\n", err)
+	}
 	return c
 }
 
-func newClient(index *int, count int, newClient func(index int) (interface{}, error)) interface{} {
-	i := *index
-	cli, err := newClient(*index)
-	for err != nil {
-		*index = (*index + 1) % count
-		if *index == i {
-			panic(fmt.Errorf("can't initialize client"))
-		}
-		cli, err = newClient(*index)
+func dialMain(ctx context.Context, seed string) (interface{}, error) {
+	cli, err := rpcclient.New(ctx, seed, rpcclient.Options{DialTimeout: dialTimeout, RequestTimeout: requestTimeout})
+	if err != nil {
+		return nil, err
 	}
-	return cli
+	err = cli.Init()
+	if err != nil {
+		return nil, err
+	}
+	return cli, nil
 }
 
-func (c *ConstantClient) ensureNewClient(isMain bool) {
-	if isMain {
-		c.mClient = newClient(&c.mIndex, len(c.mainSeeds), func(index int) (interface{}, error) {
-			cli, err := rpcclient.New(context.Background(), c.mainSeeds[index], rpcclient.Options{})
-			if err != nil {
-				return nil, err
-			}
-			err = cli.Init()
-			return cli, err
-		}).(*rpcclient.Client)
-	} else {
-		c.sClient = newClient(&c.sIndex, len(c.sideSeeds), func(index int) (interface{}, error) {
-			cli, err := client.New(context.Background(), c.sideSeeds[index], client.Options{})
-			if err != nil {
-				return nil, err
-			}
-			err = cli.Init()
-			return cli, err
-		}).(*client.Client)
+func dialSide(ctx context.Context, seed string) (interface{}, error) {
+	cli, err := client.New(ctx, seed, client.Options{DialTimeout: dialTimeout, RequestTimeout: requestTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = cli.Init()
+	if err != nil {
+		return nil, err
 	}
+	return &sideClient{Client: cli, seed: seed}, nil
 }
 
-func isSideNetworkError(err error) bool {
-	_, ok := err.(*neorpc.Error)
-	return !ok
+// dialWS connects to the first available WebSocket seed from the one
+// following the last used, the connection is closed once ctx is done.
+func (c *ConstantClient) dialWS(ctx context.Context) (*rpcclient.WSClient, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var err error
+	for i := 0; i < len(c.wsSeeds); i++ {
+		seed := c.wsSeeds[c.wsIndex]
+		var ws *rpcclient.WSClient
+		ws, err = rpcclient.NewWS(ctx, seed, rpcclient.WSOptions{Options: rpcclient.Options{DialTimeout: dialTimeout}})
+		if err == nil {
+			return ws, nil
+		}
+		metrics.AddRPCError(seed)
+		c.wsIndex = (c.wsIndex + 1) % len(c.wsSeeds)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoAvailableSeed, err)
 }
 
-func isMainNetworkError(err error) bool {
-	_, ok := err.(*response.Error)
-	return !ok
+// SwitchMainSeed moves to another main seed, e.g. when the current one
+// returns invalid data. The current seed is unhealthy until the next check.
+func (c *ConstantClient) SwitchMainSeed(ctx context.Context) error {
+	c.main.pool.Demote(c.main.Index(), "invalid data")
+	c.main.Reset()
+	_, _, err := c.main.get(ctx, make(map[int]bool))
+	return err
 }
 
-func isNetworkError(err error, isMain bool) bool {
-	if isMain {
-		return isMainNetworkError(err)
+// StartHealthCheck probes seeds every interval in background, requests are
+// routed to the best healthy seed. Seeds lagging more than maxLag blocks
+// behind the highest one are unhealthy. The check is started once, the
+// client is shared by its users.
+func (c *ConstantClient) StartHealthCheck(interval time.Duration, maxLag uint32) {
+	c.health.Do(func() {
+		go func() {
+			for {
+				c.checkSeeds(c.main, maxLag)
+				c.checkSeeds(c.side, maxLag)
+				select {
+				case <-c.ctx.Done():
+					return
+				case <-time.After(interval):
+				}
+			}
+		}()
+	})
+}
+
+// Close stops health check, aborts requests in progress and closes probe
+// clients.
+func (c *ConstantClient) Close() {
+	c.cancel()
+	c.main.pool.Close()
+	c.side.pool.Close()
+}
+
+// checkSeeds updates seed statuses of the chain and moves to the best seed
+// if the current one is unhealthy or much worse.
+func (c *ConstantClient) checkSeeds(f *failover, maxLag uint32) {
+	f.pool.Check(maxLag)
+	best := f.pool.Order()[0]
+	current := f.Index()
+	statuses := f.pool.Statuses()
+	if best == current || !statuses[best].Healthy {
+		return
 	}
-	return isSideNetworkError(err)
+	if statuses[current].Healthy && statuses[current].score()-statuses[best].score() < switchMargin {
+		return
+	}
+	f.Reset()
+	if _, _, err := f.get(c.ctx, make(map[int]bool)); err != nil {
+		log.Printf("can't switch %s seed: %s\n", f.chain, err)
+	}
+}
+
+// SeedStatuses returns health of main and side seeds.
+func (c *ConstantClient) SeedStatuses() (main []SeedStatus, side []SeedStatus) {
+	return c.main.pool.Statuses(), c.side.pool.Statuses()
 }
 
-func (c *ConstantClient) ensureRequest(isMain bool, doRequest func() (interface{}, error)) (interface{}, error) {
-	retry := 5
-	var lasterr error
-	if isMain {
-		retry = len(c.mainSeeds)
-	} else {
-		retry = len(c.sideSeeds)
+// Subscribe subscribes for new main chain blocks and notifications matching
+// filters through a WebSocket seed. A signal is sent on every event, pending
+// signals are merged. The channel is closed when the socket drops or ctx is
+// done, then the caller is expected to poll and subscribe again.
+func (c *ConstantClient) Subscribe(ctx context.Context, filters []neorpc.NotificationFilter) (<-chan struct{}, error) {
+	if len(c.wsSeeds) == 0 {
+		return nil, ErrNoWSSeed
+	}
+	ws, err := c.dialWS(ctx)
+	if err != nil {
+		return nil, err
 	}
-	for retry > 0 {
-		r, err := doRequest()
+	blocks := make(chan *block.Block, wsBufferSize)
+	notifications := make(chan *mstate.ContainedNotificationEvent, wsBufferSize)
+	_, err = ws.ReceiveBlocks(nil, blocks)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("can't subscribe for blocks: %w", err)
+	}
+	for i := range filters {
+		_, err = ws.ReceiveExecutionNotifications(&filters[i], notifications)
 		if err != nil {
-			retry--
-			lasterr = err
-			if isNetworkError(err, isMain) {
-				c.ensureNewClient(false)
-				continue
-			}
+			ws.Close()
+			return nil, fmt.Errorf("can't subscribe for notifications: %w", err)
 		}
-		return r, err
 	}
-	return nil, lasterr
+	events := make(chan struct{}, 1)
+	go func() {
+		defer close(events)
+		defer ws.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.ctx.Done():
+				return
+			case _, ok := <-blocks:
+				if !ok {
+					log.Printf("main subscription dropped: %v\n", ws.GetError())
+					return
+				}
+			case _, ok := <-notifications:
+				if !ok {
+					log.Printf("main subscription dropped: %v\n", ws.GetError())
+					return
+				}
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return events, nil
+}
+
+// isMainNetworkError tells errors of main seeds from errors returned by main
+// chain, which are JSON-RPC errors of neo-go.
+func isMainNetworkError(err error) bool {
+	var rpcErr *neorpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// isSideNetworkError tells errors of side seeds from errors returned by side
+// chain, which are JSON-RPC errors of neo-go-evm.
+func isSideNetworkError(err error) bool {
+	var rpcErr *response.Error
+	return !errors.As(err, &rpcErr)
 }
 
-func (c *ConstantClient) GetApplicationLog(txid util.Uint256) (*mresult.ApplicationLog, error) {
-	r, err := c.ensureRequest(true, func() (interface{}, error) {
-		return c.mClient.GetApplicationLog(txid, nil)
+func (c *ConstantClient) mainRequest(ctx context.Context, request func(cli *rpcclient.Client) (interface{}, error)) (interface{}, error) {
+	return c.main.Do(ctx, func(cli interface{}) (interface{}, error) {
+		return request(cli.(*rpcclient.Client))
+	})
+}
+
+func (c *ConstantClient) sideRequest(ctx context.Context, request func(cli *sideClient) (interface{}, error)) (interface{}, error) {
+	return c.side.Do(ctx, func(cli interface{}) (interface{}, error) {
+		return request(cli.(*sideClient))
+	})
+}
+
+func (c *ConstantClient) GetApplicationLog(ctx context.Context, txid util.Uint256) (*mresult.ApplicationLog, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetApplicationLog(txid, nil)
 	})
 	if err != nil {
 		return nil, err
@@ -127,9 +297,21 @@ func (c *ConstantClient) GetApplicationLog(txid util.Uint256) (*mresult.Applicat
 	return r.(*mresult.ApplicationLog), nil
 }
 
-func (c *ConstantClient) GetBlock(index uint32) (*block.Block, error) {
-	r, err := c.ensureRequest(true, func() (interface{}, error) {
-		return c.mClient.GetBlockByIndex(index)
+// GetApplicationLogQuorum returns application log of txid which m of n main
+// seeds return.
+func (c *ConstantClient) GetApplicationLogQuorum(ctx context.Context, txid util.Uint256, n, m int) (*mresult.ApplicationLog, error) {
+	rs, err := c.main.Quorum(ctx, n, m, func(cli interface{}) (interface{}, error) {
+		return cli.(*rpcclient.Client).GetApplicationLog(txid, nil)
+	}, jsonHash)
+	if err != nil {
+		return nil, err
+	}
+	return rs[0].(*mresult.ApplicationLog), nil
+}
+
+func (c *ConstantClient) GetBlock(ctx context.Context, index uint32) (*block.Block, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetBlockByIndex(index)
 	})
 	if err != nil {
 		return nil, err
@@ -137,9 +319,19 @@ func (c *ConstantClient) GetBlock(index uint32) (*block.Block, error) {
 	return r.(*block.Block), nil
 }
 
-func (c *ConstantClient) GetBlockCount() (uint32, error) {
-	r, err := c.ensureRequest(true, func() (interface{}, error) {
-		return c.mClient.GetBlockCount()
+func (c *ConstantClient) GetBlockCount(ctx context.Context) (uint32, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetBlockCount()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return r.(uint32), nil
+}
+
+func (c *ConstantClient) GetTransactionHeight(ctx context.Context, txid util.Uint256) (uint32, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetTransactionHeight(txid)
 	})
 	if err != nil {
 		return 0, err
@@ -147,9 +339,9 @@ func (c *ConstantClient) GetBlockCount() (uint32, error) {
 	return r.(uint32), nil
 }
 
-func (c *ConstantClient) GetStateRoot(index uint32) (*mstate.MPTRoot, error) {
-	r, err := c.ensureRequest(true, func() (interface{}, error) {
-		return c.mClient.GetStateRootByHeight(index)
+func (c *ConstantClient) GetStateRoot(ctx context.Context, index uint32) (*mstate.MPTRoot, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetStateRootByHeight(index)
 	})
 	if err != nil {
 		return nil, err
@@ -157,15 +349,44 @@ func (c *ConstantClient) GetStateRoot(index uint32) (*mstate.MPTRoot, error) {
 	return r.(*mstate.MPTRoot), nil
 }
 
+// GetStateRootQuorum returns state root at index which m of n main seeds
+// return, roots are matched by hash since seeds may get witness at different
+// time.
+func (c *ConstantClient) GetStateRootQuorum(ctx context.Context, index uint32, n, m int) (*mstate.MPTRoot, error) {
+	rs, err := c.main.Quorum(ctx, n, m, func(cli interface{}) (interface{}, error) {
+		return cli.(*rpcclient.Client).GetStateRootByHeight(index)
+	}, func(r interface{}) string {
+		return r.(*mstate.MPTRoot).Root.StringLE()
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		if root := r.(*mstate.MPTRoot); len(root.Witness) > 0 {
+			return root, nil
+		}
+	}
+	return rs[0].(*mstate.MPTRoot), nil
+}
+
+// jsonHash identifies results by hash of their JSON.
+func jsonHash(r interface{}) string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "invalid: " + err.Error()
+	}
+	return hash.Sha256(b).StringLE()
+}
+
 func proofToBytes(proof *mresult.ProofWithKey) []byte {
 	w := mio.NewBufBinWriter()
 	proof.EncodeBinary(w.BinWriter)
 	return w.Bytes()
 }
 
-func (c *ConstantClient) GetProof(rootHash util.Uint256, contractHash util.Uint160, key []byte) ([]byte, error) {
-	r, err := c.ensureRequest(true, func() (interface{}, error) {
-		return c.mClient.GetProof(rootHash, contractHash, key)
+func (c *ConstantClient) GetProof(ctx context.Context, rootHash util.Uint256, contractHash util.Uint160, key []byte) ([]byte, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetProof(rootHash, contractHash, key)
 	})
 	if err != nil {
 		return nil, err
@@ -174,9 +395,105 @@ func (c *ConstantClient) GetProof(rootHash util.Uint256, contractHash util.Uint1
 	return proofToBytes(res), nil
 }
 
-func (c *ConstantClient) Eth_NativeContract(name string) (*state.NativeContract, error) {
-	r, err := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.GetNativeContracts()
+func (c *ConstantClient) GetContractStateByHash(ctx context.Context, hash util.Uint160) (*mstate.Contract, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetContractStateByHash(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*mstate.Contract), nil
+}
+
+func (c *ConstantClient) GetVersion(ctx context.Context) (*mresult.Version, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.GetVersion()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*mresult.Version), nil
+}
+
+// GetDesignatedByRole returns the keys designated for role at index by
+// RoleManagement contract.
+func (c *ConstantClient) GetDesignatedByRole(ctx context.Context, role noderoles.Role, index uint32) (keys.PublicKeys, error) {
+	return rolemgmt.NewReader(invoker.New(c.RPCActor(ctx), nil)).GetDesignatedByRole(role, index)
+}
+
+func (c *ConstantClient) InvokeScript(ctx context.Context, script []byte, signers []transaction.Signer) (*mresult.Invoke, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.InvokeScript(script, signers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*mresult.Invoke), nil
+}
+
+func (c *ConstantClient) InvokeFunction(ctx context.Context, contract util.Uint160, operation string, params []smartcontract.Parameter, signers []transaction.Signer) (*mresult.Invoke, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.InvokeFunction(contract, operation, params, signers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*mresult.Invoke), nil
+}
+
+func (c *ConstantClient) InvokeContractVerify(ctx context.Context, contract util.Uint160, params []smartcontract.Parameter, signers []transaction.Signer, witnesses ...transaction.Witness) (*mresult.Invoke, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.InvokeContractVerify(contract, params, signers, witnesses...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*mresult.Invoke), nil
+}
+
+func (c *ConstantClient) TerminateSession(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.TerminateSession(sessionID)
+	})
+	if err != nil {
+		return false, err
+	}
+	return r.(bool), nil
+}
+
+func (c *ConstantClient) TraverseIterator(ctx context.Context, sessionID, iteratorID uuid.UUID, maxItemsCount int) ([]stackitem.Item, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.TraverseIterator(sessionID, iteratorID, maxItemsCount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.([]stackitem.Item), nil
+}
+
+func (c *ConstantClient) CalculateNetworkFee(ctx context.Context, tx *transaction.Transaction) (int64, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.CalculateNetworkFee(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return r.(int64), nil
+}
+
+func (c *ConstantClient) SendRawTransaction(ctx context.Context, tx *transaction.Transaction) (util.Uint256, error) {
+	r, err := c.mainRequest(ctx, func(cli *rpcclient.Client) (interface{}, error) {
+		return cli.SendRawTransaction(tx)
+	})
+	if err != nil {
+		return util.Uint256{}, err
+	}
+	return r.(util.Uint256), nil
+}
+
+func (c *ConstantClient) Eth_NativeContract(ctx context.Context, name string) (*state.NativeContract, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.GetNativeContracts()
 	})
 	if err != nil {
 		return nil, err
@@ -190,30 +507,72 @@ func (c *ConstantClient) Eth_NativeContract(name string) (*state.NativeContract,
 	return nil, nil
 }
 
-func (c *ConstantClient) Eth_ChainId() uint64 {
-	r, _ := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_ChainId()
+func (c *ConstantClient) Eth_ChainId(ctx context.Context) (uint64, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_ChainId()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return r.(uint64), nil
+}
+
+func (c *ConstantClient) Eth_GasPrice(ctx context.Context) (*big.Int, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_GasPrice()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*big.Int), nil
+}
+
+// Eth_BaseFee returns base fee per gas of the latest side chain block, it's
+// zero if the chain doesn't charge base fee.
+func (c *ConstantClient) Eth_BaseFee(ctx context.Context) (*big.Int, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		count, err := cli.GetBlockCount()
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, errors.New("empty side chain")
+		}
+		b, err := cli.Eth_GetBlockByNumber(count - 1)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetUint64(uint64(b.BaseFeePerGas)), nil
 	})
-	return r.(uint64)
+	if err != nil {
+		return nil, err
+	}
+	return r.(*big.Int), nil
 }
 
-func (c *ConstantClient) Eth_GasPrice() *big.Int {
-	r, _ := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_GasPrice()
+func (c *ConstantClient) Eth_GetBalance(ctx context.Context, address common.Address) (*big.Int, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_GetBalance(address)
 	})
-	return r.(*big.Int)
+	if err != nil {
+		return nil, err
+	}
+	return r.(*big.Int), nil
 }
 
-func (c *ConstantClient) Eth_GetTransactionCount(address common.Address) uint64 {
-	r, _ := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_GetTransactionCount(address)
+func (c *ConstantClient) Eth_GetTransactionCount(ctx context.Context, address common.Address) (uint64, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_GetTransactionCount(address)
 	})
-	return r.(uint64)
+	if err != nil {
+		return 0, err
+	}
+	return r.(uint64), nil
 }
 
-func (c *ConstantClient) Eth_EstimateGas(tx *result.TransactionObject) (uint64, error) {
-	r, err := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_EstimateGas(tx)
+func (c *ConstantClient) Eth_EstimateGas(ctx context.Context, tx *result.TransactionObject) (uint64, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_EstimateGas(tx)
 	})
 	if err != nil {
 		return 0, err
@@ -221,9 +580,19 @@ func (c *ConstantClient) Eth_EstimateGas(tx *result.TransactionObject) (uint64,
 	return r.(uint64), nil
 }
 
-func (c *ConstantClient) Eth_SendRawTransaction(rawTx []byte) (common.Hash, error) {
-	r, err := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_SendRawTransaction(rawTx)
+func (c *ConstantClient) Eth_Call(ctx context.Context, tx *result.TransactionObject) ([]byte, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_Call(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.([]byte), nil
+}
+
+func (c *ConstantClient) Eth_SendRawTransaction(ctx context.Context, rawTx []byte) (common.Hash, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_SendRawTransaction(rawTx)
 	})
 	if err != nil {
 		return common.Hash{}, err
@@ -231,9 +600,124 @@ func (c *ConstantClient) Eth_SendRawTransaction(rawTx []byte) (common.Hash, erro
 	return r.(common.Hash), nil
 }
 
-func (c *ConstantClient) Eth_GetTransactionByHash(hash common.Hash) *result.TransactionOutputRaw {
-	r, _ := c.ensureRequest(false, func() (interface{}, error) {
-		return c.sClient.Eth_GetTransactionByHash(hash)
+func (c *ConstantClient) Eth_GetTransactionByHash(ctx context.Context, hash common.Hash) (*result.TransactionOutputRaw, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_GetTransactionByHash(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*result.TransactionOutputRaw), nil
+}
+
+func (c *ConstantClient) Eth_GetTransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.Eth_GetTransactionReceipt(hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*types.Receipt), nil
+}
+
+func (c *ConstantClient) Eth_GetBlock(ctx context.Context, index uint32) (*sblock.Block, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.GetBlockByIndex(index)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*sblock.Block), nil
+}
+
+func (c *ConstantClient) Eth_GetBlockCount(ctx context.Context) (uint32, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.GetBlockCount()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return r.(uint32), nil
+}
+
+func (c *ConstantClient) Eth_GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		return cli.GetStateRootByHeight(index)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.(*state.MPTRoot), nil
+}
+
+// Eth_GetState returns historical storage item of side chain contract.
+func (c *ConstantClient) Eth_GetState(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		var resp []byte
+		err := cli.rawRequest(ctx, "getstate", []interface{}{rootHash.String(), address.String(), hex.EncodeToString(key)}, &resp)
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.([]byte), nil
+}
+
+// Eth_GetProof returns serialized side chain MPT proof which can be verified
+// by main chain bridge contract.
+func (c *ConstantClient) Eth_GetProof(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error) {
+	r, err := c.sideRequest(ctx, func(cli *sideClient) (interface{}, error) {
+		resp := new(result.ProofWithKey)
+		err := cli.rawRequest(ctx, "getproof", []interface{}{rootHash.String(), address.String(), hex.EncodeToString(key)}, resp)
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := r.(*result.ProofWithKey)
+	w := mio.NewBufBinWriter()
+	w.WriteVarBytes(res.Key)
+	w.WriteVarUint(uint64(len(res.Proof)))
+	for _, p := range res.Proof {
+		w.WriteVarBytes(p)
+	}
+	return w.Bytes(), nil
+}
+
+// rawRequest performs side chain requests which are not exposed by side
+// chain rpc client, hex encoded parameters are expected by these methods.
+func (c *sideClient) rawRequest(ctx context.Context, method string, params []interface{}, v interface{}) error {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(&request.Raw{
+		JSONRPC:   request.JSONRPCVersion,
+		Method:    method,
+		RawParams: params,
+		ID:        1,
 	})
-	return r.(*result.TransactionOutputRaw)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.seed, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	cli := http.Client{Timeout: sideRequestTimeout}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw := new(response.Raw)
+	err = json.NewDecoder(resp.Body).Decode(raw)
+	if err != nil {
+		return fmt.Errorf("can't decode %s response: %w", method, err)
+	}
+	if raw.Error != nil {
+		return raw.Error
+	}
+	if raw.Result == nil {
+		return errors.New("no result returned")
+	}
+	return json.Unmarshal(raw.Result, v)
 }
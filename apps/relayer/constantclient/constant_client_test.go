@@ -0,0 +1,302 @@
+package constantclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	sresult "github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	mstate "github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	mresult "github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/trigger"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	"github.com/nspcc-dev/neo-go/pkg/vm/vmstate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rpcServer is a fake JSON-RPC seed answering methods with fixed results,
+// unknown methods are answered with JSON-RPC errors. Connections are dropped
+// without response while drop is set, responses are sent after delay.
+type rpcServer struct {
+	*httptest.Server
+	drop    atomic.Bool
+	delay   atomic.Int64
+	mtx     sync.Mutex
+	results map[string]interface{}
+}
+
+// set sets result of method, method is unknown if result is nil.
+func (s *rpcServer) set(method string, result interface{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if result == nil {
+		delete(s.results, method)
+		return
+	}
+	s.results[method] = result
+}
+
+func newRPCServer(t *testing.T, results map[string]interface{}) *rpcServer {
+	s := &rpcServer{results: results}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.drop.Load() {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		time.Sleep(time.Duration(s.delay.Load()))
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		s.mtx.Lock()
+		result, ok := s.results[req.Method]
+		s.mtx.Unlock()
+		if ok {
+			resp["result"] = result
+		} else {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "Method not found"}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newMainServer(t *testing.T, count uint32) *rpcServer {
+	return newRPCServer(t, map[string]interface{}{
+		"getversion":         mresult.Version{Protocol: mresult.Protocol{Network: 42}},
+		"getnativecontracts": []interface{}{},
+		"getblockcount":      count,
+	})
+}
+
+func newSideServer(t *testing.T, count uint32) *rpcServer {
+	return newRPCServer(t, map[string]interface{}{
+		"getversion":    sresult.Version{Protocol: sresult.Protocol{ChainID: 53, MillisecondsPerBlock: 15000}},
+		"getblockcount": count,
+	})
+}
+
+func TestMainFailover(t *testing.T) {
+	a, b := newMainServer(t, 5), newMainServer(t, 10)
+	side := newSideServer(t, 7)
+	c := New([]string{a.URL, b.URL}, []string{side.URL}, nil)
+	defer c.Close()
+
+	count, err := c.GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), count)
+
+	a.drop.Store(true)
+	count, err = c.GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), count)
+	assert.Equal(t, b.URL, c.main.Seed())
+	main, _ := c.SeedStatuses()
+	assert.False(t, main[0].Healthy)
+	assert.True(t, main[1].Healthy)
+	// side chain is not affected by main seeds
+	assert.Equal(t, side.URL, c.side.Seed())
+	count, err = c.Eth_GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), count)
+
+	// failed seed is used again once it's back and b drops
+	a.drop.Store(false)
+	b.drop.Store(true)
+	count, err = c.GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), count)
+}
+
+func TestSideFailover(t *testing.T) {
+	main := newMainServer(t, 5)
+	a, b := newSideServer(t, 7), newSideServer(t, 8)
+	c := New([]string{main.URL}, []string{a.URL, b.URL}, nil)
+	defer c.Close()
+
+	a.drop.Store(true)
+	count, err := c.Eth_GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(8), count)
+	assert.Equal(t, b.URL, c.side.Seed())
+	assert.Equal(t, main.URL, c.main.Seed())
+}
+
+func TestChainErrorNoFailover(t *testing.T) {
+	a, b := newMainServer(t, 5), newMainServer(t, 10)
+	sa, sb := newSideServer(t, 7), newSideServer(t, 8)
+	c := New([]string{a.URL, b.URL}, []string{sa.URL, sb.URL}, nil)
+	defer c.Close()
+
+	// errors returned by chains are not seed failures
+	_, err := c.GetTransactionHeight(context.Background(), [32]byte{1})
+	var mainErr *neorpc.Error
+	require.ErrorAs(t, err, &mainErr)
+	assert.Equal(t, a.URL, c.main.Seed())
+
+	_, err = c.Eth_GetBlock(context.Background(), 1)
+	var sideErr *response.Error
+	require.ErrorAs(t, err, &sideErr)
+	assert.Equal(t, sa.URL, c.side.Seed())
+
+	// side errors are returned instead of panics
+	_, err = c.Eth_ChainId(context.Background())
+	require.ErrorAs(t, err, &sideErr)
+
+	main, side := c.SeedStatuses()
+	assert.True(t, main[0].Healthy)
+	assert.True(t, side[0].Healthy)
+}
+
+func TestFailoverBackoff(t *testing.T) {
+	a, b := newMainServer(t, 5), newMainServer(t, 10)
+	side := newSideServer(t, 7)
+	c := New([]string{a.URL, b.URL}, []string{side.URL}, nil)
+	defer c.Close()
+
+	a.drop.Store(true)
+	b.drop.Store(true)
+	start := time.Now()
+	_, err := c.GetBlockCount(context.Background())
+	require.ErrorIs(t, err, ErrNoAvailableSeed)
+	// rounds are backed off by retryDelay and 2*retryDelay
+	assert.GreaterOrEqual(t, time.Since(start), 3*retryDelay)
+
+	// seeds coming back during backoff are used
+	go func() {
+		time.Sleep(retryDelay / 2)
+		b.drop.Store(false)
+	}()
+	count, err := c.GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), count)
+}
+
+func TestFailoverClose(t *testing.T) {
+	a := newMainServer(t, 5)
+	side := newSideServer(t, 7)
+	c := New([]string{a.URL}, []string{side.URL}, nil)
+
+	a.drop.Store(true)
+	go func() {
+		time.Sleep(retryDelay / 2)
+		c.Close()
+	}()
+	start := time.Now()
+	_, err := c.GetBlockCount(context.Background())
+	require.True(t, errors.Is(err, context.Canceled), err)
+	assert.Less(t, time.Since(start), 3*retryDelay)
+}
+
+func TestCallContext(t *testing.T) {
+	a := newMainServer(t, 5)
+	side := newSideServer(t, 7)
+	c := New([]string{a.URL}, []string{side.URL}, nil)
+	defer c.Close()
+
+	// hanging call is aborted by the caller deadline
+	a.delay.Store(int64(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetBlockCount(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Eth_GetBlockCount(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// seeds are not failed by callers giving up
+	main, _ := c.SeedStatuses()
+	assert.True(t, main[0].Healthy)
+	a.delay.Store(0)
+	count, err := c.GetBlockCount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(5), count)
+}
+
+func newQuorumServer(t *testing.T, log *mresult.ApplicationLog, root *mstate.MPTRoot) *rpcServer {
+	s := newMainServer(t, 10)
+	s.set("getapplicationlog", log)
+	s.set("getstateroot", root)
+	return s
+}
+
+func TestQuorum(t *testing.T) {
+	txid := util.Uint256{1}
+	log := &mresult.ApplicationLog{
+		Container:     txid,
+		IsTransaction: true,
+		Executions: []mstate.Execution{{
+			Trigger: trigger.Application,
+			VMState: vmstate.Halt,
+			Stack:   []stackitem.Item{},
+			Events:  []mstate.NotificationEvent{},
+		}},
+	}
+	forged := *log
+	forged.Executions = []mstate.Execution{log.Executions[0]}
+	forged.Executions[0].GasConsumed = 1
+	root := &mstate.MPTRoot{Index: 1, Root: util.Uint256{2}, Witness: []transaction.Witness{}}
+	signed := *root
+	signed.Witness = []transaction.Witness{{InvocationScript: []byte{1}, VerificationScript: []byte{2}}}
+
+	a, b, c := newQuorumServer(t, log, root), newQuorumServer(t, log, &signed), newQuorumServer(t, &forged, root)
+	side := newSideServer(t, 7)
+	client := New([]string{a.URL, b.URL, c.URL}, []string{side.URL}, nil)
+	defer client.Close()
+
+	res, err := client.GetApplicationLogQuorum(context.Background(), txid, 3, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), uint64(res.Executions[0].GasConsumed))
+	main, _ := client.SeedStatuses()
+	assert.True(t, main[0].Healthy)
+	assert.True(t, main[1].Healthy)
+	assert.False(t, main[2].Healthy)
+	assert.Equal(t, "quorum mismatch", main[2].Reason)
+
+	_, err = client.GetApplicationLogQuorum(context.Background(), txid, 3, 3)
+	require.ErrorIs(t, err, ErrNoQuorum)
+
+	// roots match by hash, the witnessed one is returned
+	r, err := client.GetStateRootQuorum(context.Background(), 1, 3, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(r.Witness))
+
+	// dropped seeds are replaced by the following ones
+	a.drop.Store(true)
+	_, err = client.GetApplicationLogQuorum(context.Background(), txid, 2, 2)
+	require.ErrorIs(t, err, ErrNoQuorum)
+	c.set("getapplicationlog", log)
+	_, err = client.GetApplicationLogQuorum(context.Background(), txid, 2, 2)
+	require.NoError(t, err)
+
+	// errors returned by chain are answers
+	b.set("getapplicationlog", nil)
+	c.set("getapplicationlog", nil)
+	_, err = client.GetApplicationLogQuorum(context.Background(), txid, 2, 2)
+	var rpcErr *neorpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+}
@@ -0,0 +1,325 @@
+package constantclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+)
+
+const (
+	// dialTimeout and requestTimeout bound connecting to a seed and a single
+	// request to it, hanging seeds are failed over like dropped ones.
+	dialTimeout    = 4 * time.Second
+	requestTimeout = 20 * time.Second
+	// callTimeout bounds a call with all its retries unless the caller sets
+	// an earlier deadline.
+	callTimeout = time.Minute
+	// failed requests are retried with every seed for maxRounds rounds,
+	// rounds are backed off exponentially from retryDelay.
+	maxRounds     = 3
+	retryDelay    = 200 * time.Millisecond
+	maxRetryDelay = 5 * time.Second
+)
+
+// failover is the client state of one chain. Requests go to the client of the
+// best seed, the seed is demoted on network errors and requests are retried
+// with the following seeds.
+type failover struct {
+	chain string
+	seeds []string
+	pool  *seedPool
+	// ctx is the lifetime of dialed clients, calls are aborted once it's
+	// done.
+	ctx context.Context
+	// dial connects to seed, isNetworkError tells errors of the seed from
+	// errors returned by the chain.
+	dial           func(ctx context.Context, seed string) (interface{}, error)
+	isNetworkError func(err error) bool
+
+	mtx    sync.RWMutex
+	index  int
+	client interface{}
+	// clients are clients of other seeds dialed for quorum reads
+	clients map[int]interface{}
+}
+
+func newFailover(ctx context.Context, chain string, seeds []string, pool *seedPool, dial func(context.Context, string) (interface{}, error), isNetworkError func(error) bool) *failover {
+	return &failover{
+		chain:          chain,
+		seeds:          seeds,
+		pool:           pool,
+		ctx:            ctx,
+		dial:           dial,
+		isNetworkError: isNetworkError,
+		clients:        make(map[int]interface{}),
+	}
+}
+
+// Seed returns the seed in use.
+func (f *failover) Seed() string {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.seeds[f.index]
+}
+
+// Index returns the index of the seed in use.
+func (f *failover) Index() int {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.index
+}
+
+// Reset drops the client, the best seed is dialed on the next request.
+func (f *failover) Reset() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.client = nil
+}
+
+// get returns the client in use unless its seed failed already, otherwise
+// seeds not failed are dialed from the best one.
+func (f *failover) get(ctx context.Context, failed map[int]bool) (int, interface{}, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.client != nil && !failed[f.index] {
+		return f.index, f.client, nil
+	}
+	err := errors.New("all seeds failed")
+	for _, i := range f.pool.Order() {
+		if failed[i] {
+			continue
+		}
+		var cli interface{}
+		cli, err = f.dial(f.ctx, f.seeds[i])
+		if err == nil {
+			if i != f.index {
+				log.Printf("switch %s seed from %s to %s\n", f.chain, f.seeds[f.index], f.seeds[i])
+			}
+			f.index, f.client = i, cli
+			return i, cli, nil
+		}
+		failed[i] = true
+		f.fail(i, err)
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: %s", ErrNoAvailableSeed, err)
+}
+
+// fail demotes the seed, f.mtx must be held.
+func (f *failover) fail(index int, err error) {
+	metrics.AddRPCError(f.seeds[index])
+	f.pool.Demote(index, err.Error())
+}
+
+// drop demotes the seed cli is of and drops cli if it's still in use.
+func (f *failover) drop(index int, cli interface{}, err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.fail(index, err)
+	if f.index == index && f.client == cli {
+		f.client = nil
+	}
+	if f.clients[index] == cli {
+		delete(f.clients, index)
+	}
+}
+
+// clientOf returns a client of seed at index, it's dialed if needed.
+func (f *failover) clientOf(ctx context.Context, index int) (interface{}, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.client != nil && f.index == index {
+		return f.client, nil
+	}
+	if cli, ok := f.clients[index]; ok {
+		return cli, nil
+	}
+	cli, err := f.dial(f.ctx, f.seeds[index])
+	if err != nil {
+		f.fail(index, err)
+		return nil, err
+	}
+	f.clients[index] = cli
+	return cli, nil
+}
+
+// callContext bounds ctx of a call by callTimeout, the call is aborted on
+// Close too.
+func (f *failover) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	go func() {
+		select {
+		case <-f.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// call sends request with cli and returns ctx error once ctx is done. Chain
+// clients don't take contexts of requests, so the abandoned request runs
+// until requestTimeout in background.
+func call(ctx context.Context, cli interface{}, request func(cli interface{}) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type response struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan response, 1)
+	go func() {
+		r, err := request(cli)
+		done <- response{result: r, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// answer is a result of quorum request from a seed, key identifies equal
+// results.
+type answer struct {
+	index  int
+	result interface{}
+	err    error
+	key    string
+}
+
+// Quorum sends request to n seeds from the best one and returns results of
+// the largest group of equal keys if it has m results at least. Seeds
+// failing are replaced with the following ones, errors returned by chain are
+// results too. Seeds answering differently than the quorum are flagged and
+// demoted.
+func (f *failover) Quorum(ctx context.Context, n, m int, request func(cli interface{}) (interface{}, error), key func(result interface{}) string) ([]interface{}, error) {
+	ctx, cancel := f.callContext(ctx)
+	defer cancel()
+	order := f.pool.Order()
+	var answers []answer
+	for next := 0; len(answers) < n && next < len(order); {
+		batch := order[next:]
+		if len(batch) > n-len(answers) {
+			batch = batch[:n-len(answers)]
+		}
+		next += len(batch)
+		results := make([]*answer, len(batch))
+		var wg sync.WaitGroup
+		for j, index := range batch {
+			wg.Add(1)
+			go func(j, index int) {
+				defer wg.Done()
+				cli, err := f.clientOf(ctx, index)
+				if err != nil {
+					return
+				}
+				r, err := call(ctx, cli, request)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil && f.isNetworkError(err) {
+					f.drop(index, cli, err)
+					return
+				}
+				a := &answer{index: index, result: r, err: err}
+				if err != nil {
+					a.key = "error: " + err.Error()
+				} else {
+					a.key = key(r)
+				}
+				results[j] = a
+			}(j, index)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for _, a := range results {
+			if a != nil {
+				answers = append(answers, *a)
+			}
+		}
+	}
+	votes := make(map[string]int)
+	best := ""
+	for _, a := range answers {
+		votes[a.key]++
+		if votes[a.key] > votes[best] {
+			best = a.key
+		}
+	}
+	if votes[best] < m {
+		return nil, fmt.Errorf("%w: %d of %d %s seeds match, expect=%d", ErrNoQuorum, votes[best], len(answers), f.chain, m)
+	}
+	var results []interface{}
+	for _, a := range answers {
+		if a.key != best {
+			log.Printf("%s seed %s mismatches quorum, got=%s, expect=%s\n", f.chain, f.seeds[a.index], a.key, best)
+			metrics.AddQuorumMismatch(f.seeds[a.index])
+			f.pool.Demote(a.index, "quorum mismatch")
+			continue
+		}
+		if a.err != nil {
+			return nil, a.err
+		}
+		results = append(results, a.result)
+	}
+	return results, nil
+}
+
+// Do sends request to the seed in use. On network errors it's retried with
+// every other seed, then rounds over seeds are repeated with exponential
+// backoff until maxRounds or ctx is done. The whole call is bounded by
+// callTimeout. Errors returned by the chain are returned as they are.
+func (f *failover) Do(ctx context.Context, request func(cli interface{}) (interface{}, error)) (interface{}, error) {
+	ctx, cancel := f.callContext(ctx)
+	defer cancel()
+	delay := retryDelay
+	var lastErr error
+	for round := 0; round < maxRounds; round++ {
+		if round > 0 {
+			log.Printf("%s seeds unavailable: %s, retry in %s\n", f.chain, lastErr, delay)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+		}
+		failed := make(map[int]bool, len(f.seeds))
+		for len(failed) < len(f.seeds) {
+			index, cli, err := f.get(ctx, failed)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			r, err := call(ctx, cli, request)
+			if err == nil || !f.isNetworkError(err) {
+				return r, err
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			failed[index] = true
+			f.drop(index, cli, err)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,304 @@
+package constantclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/client"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient"
+)
+
+const (
+	probeTimeout = 4 * time.Second
+	// lagPenalty is added to latency of a seed for every block it lags
+	// behind the highest seed when seeds are scored.
+	lagPenalty = time.Second
+	// switchMargin is the score a healthy seed in use must be worse than the
+	// best one by to be switched from, so that close seeds don't flap.
+	switchMargin = 2 * lagPenalty
+)
+
+// SeedStatus is the result of the last health check of a seed.
+type SeedStatus struct {
+	Seed      string        `json:"seed"`
+	Healthy   bool          `json:"healthy"`
+	Height    uint32        `json:"height"`
+	Lag       uint32        `json:"lag"`
+	Latency   time.Duration `json:"latency"`
+	Reason    string        `json:"reason,omitempty"`
+	CheckedAt time.Time     `json:"checkedAt"`
+}
+
+func (s *SeedStatus) score() time.Duration {
+	return s.Latency + time.Duration(s.Lag)*lagPenalty
+}
+
+// seedPool scores seeds of a chain by probed height and latency. Seeds lagging
+// more than maxLag blocks or disagreeing with the majority on block hash are
+// unhealthy until the next check.
+type seedPool struct {
+	chain string
+	seeds []string
+	// height returns the latest block index of seed, hash returns the hash
+	// of block at index as a string comparable between seeds.
+	height func(seed string) (uint32, error)
+	hash   func(seed string, index uint32) (string, error)
+	// close releases probe clients, it's nil if there are none.
+	close func()
+
+	mtx      sync.RWMutex
+	statuses []SeedStatus
+}
+
+func newSeedPool(chain string, seeds []string, height func(string) (uint32, error), hash func(string, uint32) (string, error)) *seedPool {
+	p := &seedPool{
+		chain:    chain,
+		seeds:    seeds,
+		height:   height,
+		hash:     hash,
+		statuses: make([]SeedStatus, len(seeds)),
+	}
+	// unchecked seeds are used in the configured order
+	for i, seed := range seeds {
+		p.statuses[i] = SeedStatus{Seed: seed, Healthy: true}
+	}
+	return p
+}
+
+// newMainSeedPool probes main seeds with clients made with ctx on the first
+// check, no client is made once ctx is done.
+func newMainSeedPool(ctx context.Context, seeds []string) *seedPool {
+	var (
+		mtx     sync.Mutex
+		clients = make(map[string]*rpcclient.Client)
+	)
+	get := func(seed string) (*rpcclient.Client, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if cli, ok := clients[seed]; ok {
+			return cli, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cli, err := rpcclient.New(ctx, seed, rpcclient.Options{DialTimeout: probeTimeout, RequestTimeout: probeTimeout})
+		if err != nil {
+			return nil, err
+		}
+		clients[seed] = cli
+		return cli, nil
+	}
+	p := newSeedPool("main", seeds, func(seed string) (uint32, error) {
+		cli, err := get(seed)
+		if err != nil {
+			return 0, err
+		}
+		count, err := cli.GetBlockCount()
+		if err != nil {
+			return 0, err
+		}
+		return count - 1, nil
+	}, func(seed string, index uint32) (string, error) {
+		cli, err := get(seed)
+		if err != nil {
+			return "", err
+		}
+		h, err := cli.GetBlockHash(index)
+		return h.StringLE(), err
+	})
+	p.close = func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for seed, cli := range clients {
+			cli.Close()
+			delete(clients, seed)
+		}
+	}
+	return p
+}
+
+// newSideSeedPool probes side seeds with clients made with ctx on the first
+// check, no client is made once ctx is done. Side clients have no Close, their
+// requests are stopped by ctx.
+func newSideSeedPool(ctx context.Context, seeds []string) *seedPool {
+	var (
+		mtx     sync.Mutex
+		clients = make(map[string]*client.Client)
+	)
+	get := func(seed string) (*client.Client, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if cli, ok := clients[seed]; ok {
+			return cli, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cli, err := client.New(ctx, seed, client.Options{DialTimeout: probeTimeout, RequestTimeout: probeTimeout})
+		if err != nil {
+			return nil, err
+		}
+		clients[seed] = cli
+		return cli, nil
+	}
+	p := newSeedPool("side", seeds, func(seed string) (uint32, error) {
+		cli, err := get(seed)
+		if err != nil {
+			return 0, err
+		}
+		count, err := cli.GetBlockCount()
+		if err != nil {
+			return 0, err
+		}
+		return count - 1, nil
+	}, func(seed string, index uint32) (string, error) {
+		cli, err := get(seed)
+		if err != nil {
+			return "", err
+		}
+		h, err := cli.GetBlockHash(index)
+		return h.String(), err
+	})
+	p.close = func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for seed := range clients {
+			delete(clients, seed)
+		}
+	}
+	return p
+}
+
+// Close releases probe clients, it's called once their ctx is done.
+func (p *seedPool) Close() {
+	if p.close != nil {
+		p.close()
+	}
+}
+
+// Statuses returns a copy of seed statuses in the configured order.
+func (p *seedPool) Statuses() []SeedStatus {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return append([]SeedStatus(nil), p.statuses...)
+}
+
+// Order returns seed indexes from the best one, unhealthy seeds come last.
+func (p *seedPool) Order() []int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	order := make([]int, len(p.statuses))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := &p.statuses[order[i]], &p.statuses[order[j]]
+		if a.Healthy != b.Healthy {
+			return a.Healthy
+		}
+		return a.score() < b.score()
+	})
+	return order
+}
+
+// Demote marks seed at index unhealthy until the next check, e.g. when it
+// returns invalid data.
+func (p *seedPool) Demote(index int, reason string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	s := &p.statuses[index]
+	if s.Healthy {
+		log.Printf("%s seed %s unhealthy: %s\n", p.chain, s.Seed, reason)
+	}
+	s.Healthy = false
+	s.Reason = reason
+}
+
+// Check probes all seeds and updates their statuses.
+func (p *seedPool) Check(maxLag uint32) {
+	statuses := make([]SeedStatus, len(p.seeds))
+	var wg sync.WaitGroup
+	for i, seed := range p.seeds {
+		wg.Add(1)
+		go func(i int, seed string) {
+			defer wg.Done()
+			start := time.Now()
+			height, err := p.height(seed)
+			statuses[i] = SeedStatus{Seed: seed, Height: height, Latency: time.Since(start), CheckedAt: start}
+			if err != nil {
+				metrics.AddRPCError(seed)
+				statuses[i].Reason = fmt.Sprintf("can't get height: %s", err)
+			}
+		}(i, seed)
+	}
+	wg.Wait()
+
+	var best uint32
+	for i := range statuses {
+		if statuses[i].Reason == "" && statuses[i].Height > best {
+			best = statuses[i].Height
+		}
+	}
+	common := best
+	for i := range statuses {
+		s := &statuses[i]
+		if s.Reason != "" {
+			continue
+		}
+		s.Lag = best - s.Height
+		if s.Lag > maxLag {
+			s.Reason = fmt.Sprintf("lagging %d blocks", s.Lag)
+			continue
+		}
+		if s.Height < common {
+			common = s.Height
+		}
+	}
+
+	// seeds on a fork disagree with the majority on the block all have
+	hashes := make([]string, len(statuses))
+	votes := make(map[string]int)
+	majority := ""
+	for i := range statuses {
+		s := &statuses[i]
+		if s.Reason != "" {
+			continue
+		}
+		h, err := p.hash(s.Seed, common)
+		if err != nil {
+			metrics.AddRPCError(s.Seed)
+			s.Reason = fmt.Sprintf("can't get block hash %d: %s", common, err)
+			continue
+		}
+		hashes[i] = h
+		votes[h]++
+		if votes[h] > votes[majority] {
+			majority = h
+		}
+	}
+	for i := range statuses {
+		s := &statuses[i]
+		if s.Reason == "" && hashes[i] != majority {
+			s.Reason = fmt.Sprintf("forked at %d, hash=%s, expect=%s", common, hashes[i], majority)
+		}
+		s.Healthy = s.Reason == ""
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for i := range statuses {
+		prev, s := &p.statuses[i], &statuses[i]
+		switch {
+		case s.Healthy && (!prev.Healthy || prev.CheckedAt.IsZero()):
+			log.Printf("%s seed %s healthy, height=%d, latency=%s\n", p.chain, s.Seed, s.Height, s.Latency)
+		case !s.Healthy && (prev.Healthy || prev.Reason != s.Reason):
+			log.Printf("%s seed %s unhealthy: %s\n", p.chain, s.Seed, s.Reason)
+		}
+	}
+	p.statuses = statuses
+}
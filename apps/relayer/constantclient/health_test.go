@@ -0,0 +1,91 @@
+package constantclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSeed struct {
+	height  uint32
+	fork    uint32 // blocks from fork are different, zero for no fork
+	latency time.Duration
+	down    bool
+}
+
+func newTestPool(seeds map[string]*fakeSeed, names ...string) *seedPool {
+	return newSeedPool("main", names, func(seed string) (uint32, error) {
+		s := seeds[seed]
+		if s.down {
+			return 0, errors.New("connection refused")
+		}
+		time.Sleep(s.latency)
+		return s.height, nil
+	}, func(seed string, index uint32) (string, error) {
+		s := seeds[seed]
+		if s.fork != 0 && index >= s.fork {
+			return fmt.Sprintf("fork%d", index), nil
+		}
+		return fmt.Sprintf("block%d", index), nil
+	})
+}
+
+func TestSeedPool(t *testing.T) {
+	seeds := map[string]*fakeSeed{
+		"a": {height: 100, latency: 20 * time.Millisecond},
+		"b": {height: 100},
+		"c": {height: 90},
+		"d": {height: 99, fork: 95},
+		"e": {down: true},
+	}
+	p := newTestPool(seeds, "a", "b", "c", "d", "e")
+	// unchecked seeds are in the configured order
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, p.Order())
+
+	p.Check(3)
+	statuses := p.Statuses()
+	require.Equal(t, 5, len(statuses))
+	assert.True(t, statuses[0].Healthy)
+	assert.True(t, statuses[1].Healthy)
+	assert.False(t, statuses[2].Healthy)
+	assert.Equal(t, uint32(10), statuses[2].Lag)
+	assert.Contains(t, statuses[2].Reason, "lagging")
+	assert.False(t, statuses[3].Healthy)
+	assert.Contains(t, statuses[3].Reason, "forked at 99")
+	assert.False(t, statuses[4].Healthy)
+	assert.Contains(t, statuses[4].Reason, "connection refused")
+	assert.Equal(t, []int{1, 0}, p.Order()[:2])
+
+	// lag costs more than latency
+	seeds["b"].height = 98
+	p.Check(3)
+	assert.Equal(t, []int{0, 1}, p.Order()[:2])
+
+	p.Demote(0, "invalid data")
+	assert.Equal(t, 1, p.Order()[0])
+	assert.Equal(t, "invalid data", p.Statuses()[0].Reason)
+	p.Check(3)
+	assert.Equal(t, 0, p.Order()[0])
+}
+
+func TestSeedPoolClose(t *testing.T) {
+	server := newMainServer(t, 10)
+	server.set("getblockhash", "0x"+strings.Repeat("00", 32))
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newMainSeedPool(ctx, []string{server.URL})
+	p.Check(3)
+	require.True(t, p.Statuses()[0].Healthy, p.Statuses()[0].Reason)
+
+	cancel()
+	p.Close()
+	// no probe client is made once ctx is done
+	p.Check(3)
+	assert.False(t, p.Statuses()[0].Healthy)
+	assert.Contains(t, p.Statuses()[0].Reason, context.Canceled.Error())
+}
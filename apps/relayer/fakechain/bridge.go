@@ -0,0 +1,223 @@
+package fakechain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/mpt"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/native"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/crypto/hash"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/io"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// bridge emulates side chain Bridge native contract calls relayed from main
+// chain, it checks the same conditions and reports the same errors but skips
+// signature verification.
+type bridge struct {
+	contract        *state.NativeContract
+	headers         map[uint32]*block.Header
+	headerJoints    []uint32
+	stateroots      map[uint32]*state.MPTRoot
+	stateValidators map[uint32]bool
+	validatorsIndex uint32
+	minted          map[string]bool
+}
+
+func newBridge(contract *state.NativeContract) *bridge {
+	return &bridge{
+		contract:        contract,
+		headers:         make(map[uint32]*block.Header),
+		stateroots:      make(map[uint32]*state.MPTRoot),
+		stateValidators: make(map[uint32]bool),
+		minted:          make(map[string]bool),
+	}
+}
+
+// call executes bridge method in data, state is changed only if apply is set.
+func (b *bridge) call(data []byte, apply bool) error {
+	if len(data) < 4 {
+		return errors.New("invalid bridge call data")
+	}
+	method, err := b.contract.Abi.MethodById(data[:4])
+	if err != nil {
+		return err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return err
+	}
+	switch method.Name {
+	case "syncHeader":
+		return b.syncHeader(args[0].([]byte), apply)
+	case "syncStateRoot":
+		return b.syncStateRoot(args[0].([]byte), apply)
+	case "syncValidators", "syncStateRootValidatorsAddress", "requestMint":
+		headerIndex := args[0].(uint32)
+		txid := common.BytesToHash(args[1].(*big.Int).Bytes())
+		key, value, err := b.verifyState(headerIndex, txid, args[2].([]byte), args[3].(uint32), args[4].([]byte))
+		if err != nil {
+			return err
+		}
+		switch method.Name {
+		case "syncValidators":
+			return b.syncValidators(headerIndex, txid, key, value, apply)
+		case "syncStateRootValidatorsAddress":
+			return b.syncStateRootValidatorsAddress(headerIndex, key, apply)
+		default:
+			return b.requestMint(txid, key, value, apply)
+		}
+	default:
+		return fmt.Errorf("unsupported bridge method %s", method.Name)
+	}
+}
+
+func (b *bridge) syncHeader(raw []byte, apply bool) error {
+	header := new(block.Header)
+	err := io.FromByteArray(header, raw)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.headers[header.Index]; ok {
+		return native.ErrAlreadySynced
+	}
+	var joint *block.Header
+	for i := len(b.headerJoints) - 1; i >= 0; i-- {
+		if b.headerJoints[i] <= header.Index {
+			joint = b.headers[b.headerJoints[i]]
+			break
+		}
+	}
+	if joint == nil && header.Index != 0 {
+		return errors.New("genesis block unsynced")
+	}
+	if joint != nil && hash.Hash160(header.Witness.VerificationScript) != joint.NextConsensus {
+		return native.ErrInvalidSignature
+	}
+	if !apply {
+		return nil
+	}
+	b.headers[header.Index] = header
+	if joint == nil || joint.NextConsensus != header.NextConsensus {
+		b.headerJoints = append(b.headerJoints, header.Index)
+	}
+	return nil
+}
+
+func (b *bridge) syncStateRoot(raw []byte, apply bool) error {
+	stateroot := new(state.MPTRoot)
+	err := io.FromByteArray(stateroot, raw)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.stateroots[stateroot.Index]; ok {
+		return native.ErrAlreadySynced
+	}
+	if len(stateroot.Witness.VerificationScript) == 0 {
+		return native.ErrInvalidStateRoot
+	}
+	if apply {
+		b.stateroots[stateroot.Index] = stateroot
+	}
+	return nil
+}
+
+func (b *bridge) verifyState(headerIndex uint32, txid common.Hash, txProof []byte, stateIndex uint32, stateProof []byte) ([]byte, []byte, error) {
+	header, ok := b.headers[headerIndex]
+	if !ok {
+		return nil, nil, native.ErrHeaderNotFound
+	}
+	if stateIndex < headerIndex {
+		return nil, nil, native.ErrInvalidStateRoot
+	}
+	stateroot, ok := b.stateroots[stateIndex]
+	if !ok {
+		return nil, nil, native.ErrStateRootNotFound
+	}
+	if len(txProof) < 4 || (len(txProof)-4)%common.HashLength != 0 {
+		return nil, nil, native.ErrTxInexistent
+	}
+	hashes := make([]common.Hash, (len(txProof)-4)/common.HashLength)
+	for i := range hashes {
+		hashes[i] = common.BytesToHash(txProof[4+i*common.HashLength : 4+(i+1)*common.HashLength])
+	}
+	if !hash.VerifyMerkleProof(header.MerkleRoot, txid, hashes, binary.LittleEndian.Uint32(txProof)) {
+		return nil, nil, native.ErrTxInexistent
+	}
+	pwk := new(result.ProofWithKey)
+	err := io.FromByteArray(pwk, stateProof)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, ok := mpt.VerifyProof(stateroot.Root, pwk.Key, pwk.Proof)
+	if !ok {
+		return nil, nil, native.ErrInvalidMPTProof
+	}
+	return pwk.Key, value, nil
+}
+
+func (b *bridge) syncValidators(headerIndex uint32, txid common.Hash, key []byte, value []byte, apply bool) error {
+	if b.validatorsIndex > 0 && b.validatorsIndex >= headerIndex {
+		return native.ErrValidatorsOutdated
+	}
+	if len(key) != 5 || key[4] != ValidatorsKey {
+		return errors.New("not designate validators proof")
+	}
+	if len(value) < common.HashLength+1 || common.BytesToHash(value[:common.HashLength]) != txid {
+		return native.ErrInvalidMainValidatorsState
+	}
+	if apply {
+		b.validatorsIndex = headerIndex
+	}
+	return nil
+}
+
+func (b *bridge) syncStateRootValidatorsAddress(headerIndex uint32, key []byte, apply bool) error {
+	if len(key) != 9 || int32(binary.LittleEndian.Uint32(key)) != RoleManagementId || key[4] != StateValidatorRole {
+		return native.ErrInvalidMPTProof
+	}
+	index := binary.BigEndian.Uint32(key[5:])
+	if index != headerIndex+1 {
+		return native.ErrInvalidMPTProof
+	}
+	if b.stateValidators[index] {
+		return native.ErrAlreadySynced
+	}
+	if apply {
+		b.stateValidators[index] = true
+	}
+	return nil
+}
+
+func (b *bridge) requestMint(txid common.Hash, key []byte, value []byte, apply bool) error {
+	if len(key) < 5 || key[4] != DepositPrefix {
+		return native.ErrInvalidMPTProof
+	}
+	id := string(key[5:])
+	if b.minted[id] {
+		return native.ErrAlreadyMinted
+	}
+	r := io.NewBinReaderFromBuf(value)
+	var depositTx common.Hash
+	r.ReadBytes(depositTx[:])
+	r.ReadBytes(make([]byte, common.AddressLength))
+	amount := r.ReadU64LE()
+	if r.Err != nil {
+		return fmt.Errorf("invalid deposited state: %w", r.Err)
+	}
+	if depositTx != txid {
+		return native.ErrTxIdNotMatchDepositedState
+	}
+	if new(big.Int).SetUint64(amount).Cmp(native.MintThreashold) < 0 {
+		return native.ErrUnreachThreshold
+	}
+	if apply {
+		b.minted[id] = true
+	}
+	return nil
+}
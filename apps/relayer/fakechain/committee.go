@@ -0,0 +1,73 @@
+package fakechain
+
+import (
+	"sort"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/hash"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/opcode"
+)
+
+// Committee is a set of main chain keys signing headers or state roots with
+// the default multisig script.
+type Committee struct {
+	keys   []*keys.PrivateKey
+	script []byte
+	m      int
+}
+
+// NewCommittee creates committee of n random keys.
+func NewCommittee(n int) *Committee {
+	privs := make([]*keys.PrivateKey, n)
+	for i := range privs {
+		priv, err := keys.NewPrivateKey()
+		if err != nil {
+			panic(err)
+		}
+		privs[i] = priv
+	}
+	// in the order of multisig script, so that signatures are in the order
+	sort.Slice(privs, func(i, j int) bool {
+		return privs[i].PublicKey().Cmp(privs[j].PublicKey()) < 0
+	})
+	c := &Committee{keys: privs, m: smartcontract.GetDefaultHonestNodeCount(n)}
+	script, err := smartcontract.CreateDefaultMultiSigRedeemScript(c.PublicKeys())
+	if err != nil {
+		panic(err)
+	}
+	c.script = script
+	return c
+}
+
+func (c *Committee) PublicKeys() keys.PublicKeys {
+	pks := make(keys.PublicKeys, len(c.keys))
+	for i, priv := range c.keys {
+		pks[i] = priv.PublicKey()
+	}
+	return pks
+}
+
+func (c *Committee) Script() []byte {
+	return c.script
+}
+
+func (c *Committee) ScriptHash() util.Uint160 {
+	return hash.Hash160(c.script)
+}
+
+// Sign creates multisig witness of hh on network magic.
+func (c *Committee) Sign(magic uint32, hh hash.Hashable) transaction.Witness {
+	invocation := make([]byte, 0, c.m*66)
+	for _, priv := range c.keys[:c.m] {
+		sig := priv.SignHashable(magic, hh)
+		invocation = append(invocation, byte(opcode.PUSHDATA1), byte(len(sig)))
+		invocation = append(invocation, sig...)
+	}
+	return transaction.Witness{
+		InvocationScript:   invocation,
+		VerificationScript: c.script,
+	}
+}
@@ -0,0 +1,189 @@
+package fakechain
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/bigint"
+	"github.com/nspcc-dev/neo-go/pkg/io"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/callflag"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/emit"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+)
+
+const (
+	DepositPrefix      = 0x01
+	ValidatorsKey      = 0x03
+	StateValidatorRole = 4
+
+	RoleManagementId = -8
+
+	DepositedEventName         = "OnDeposited"
+	ValidatorsChangedEventName = "OnValidatorsChanged"
+	DesignationEventName       = "Designation"
+)
+
+var (
+	// RoleManagement is the main chain RoleManagement native contract hash.
+	RoleManagement = state.CreateNativeContractHash("RoleManagement")
+	// GAS is the main chain GasToken native contract hash.
+	GAS = state.CreateNativeContractHash("GasToken")
+)
+
+// Invocation is a main chain transaction, it puts storage items of contracts
+// and returns the notifications emitted. Script is the transaction script
+// calling the contract.
+type Invocation interface {
+	Script() []byte
+	Execute(c *MainChain, index uint32, txid util.Uint256) ([]state.NotificationEvent, error)
+}
+
+func appCall(contract util.Uint160, method string, args ...any) []byte {
+	w := io.NewBufBinWriter()
+	emit.AppCall(w.BinWriter, contract, method, callflag.All, args...)
+	if w.Err != nil {
+		panic(w.Err)
+	}
+	return w.Bytes()
+}
+
+// Deposit is an invocation of bridge contract deposit.
+type Deposit struct {
+	Bridge util.Uint160
+	Id     uint64
+	From   util.Uint160
+	Amount uint64
+	To     util.Uint160
+}
+
+// Script transfers GAS to bridge, the deposit is made on payment.
+func (d Deposit) Script() []byte {
+	return appCall(GAS, "transfer", d.From, d.Bridge, d.Amount, d.To)
+}
+
+func (d Deposit) Execute(c *MainChain, index uint32, txid util.Uint256) ([]state.NotificationEvent, error) {
+	id := new(big.Int).SetUint64(d.Id)
+	w := io.NewBufBinWriter()
+	w.WriteBytes(txid.BytesBE())
+	w.WriteBytes(d.From.BytesBE())
+	w.WriteU64LE(d.Amount)
+	w.WriteBytes(d.To.BytesBE())
+	err := c.putStorage(d.Bridge, append([]byte{DepositPrefix}, bigint.ToBytes(id)...), w.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return []state.NotificationEvent{{
+		ScriptHash: d.Bridge,
+		Name:       DepositedEventName,
+		Item: stackitem.NewArray([]stackitem.Item{
+			stackitem.NewBigInteger(id),
+			stackitem.NewByteArray(d.From.BytesBE()),
+			stackitem.NewBigInteger(new(big.Int).SetUint64(d.Amount)),
+			stackitem.NewByteArray(d.To.BytesBE()),
+		}),
+	}}, nil
+}
+
+// ValidatorsChange is an invocation of bridge contract changing side chain
+// validators, Keys are secp256k1 public keys.
+type ValidatorsChange struct {
+	Bridge util.Uint160
+	Keys   keys.PublicKeys
+}
+
+func (v ValidatorsChange) Script() []byte {
+	items := make([]any, len(v.Keys))
+	for i, pk := range v.Keys {
+		items[i] = pk.Bytes()
+	}
+	return appCall(v.Bridge, "changeValidators", items)
+}
+
+func (v ValidatorsChange) Execute(c *MainChain, index uint32, txid util.Uint256) ([]state.NotificationEvent, error) {
+	w := io.NewBufBinWriter()
+	w.WriteBytes(txid.BytesBE())
+	w.WriteB(byte(len(v.Keys)))
+	items := make([]stackitem.Item, len(v.Keys))
+	for i, pk := range v.Keys {
+		b := pk.Bytes()
+		w.WriteB(byte(len(b)))
+		w.WriteBytes(b)
+		items[i] = stackitem.NewByteArray(b)
+	}
+	err := c.putStorage(v.Bridge, []byte{ValidatorsKey}, w.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return []state.NotificationEvent{{
+		ScriptHash: v.Bridge,
+		Name:       ValidatorsChangedEventName,
+		Item:       stackitem.NewArray([]stackitem.Item{stackitem.NewArray(items)}),
+	}}, nil
+}
+
+// StateValidatorsDesignation is an invocation of RoleManagement designating
+// state validators, Committee signs state roots from the next block.
+type StateValidatorsDesignation struct {
+	Committee *Committee
+}
+
+func (d StateValidatorsDesignation) Script() []byte {
+	pks := d.Committee.PublicKeys()
+	items := make([]any, len(pks))
+	for i, pk := range pks {
+		items[i] = pk.Bytes()
+	}
+	return appCall(RoleManagement, "designateAsRole", int64(StateValidatorRole), items)
+}
+
+func (d StateValidatorsDesignation) Execute(c *MainChain, index uint32, txid util.Uint256) ([]state.NotificationEvent, error) {
+	pks := d.Committee.PublicKeys()
+	items := make([]stackitem.Item, len(pks))
+	for i, pk := range pks {
+		items[i] = stackitem.NewByteArray(pk.Bytes())
+	}
+	value, err := stackitem.Serialize(stackitem.NewArray(items))
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 5)
+	key[0] = StateValidatorRole
+	binary.BigEndian.PutUint32(key[1:], index+1)
+	err = c.putStorage(RoleManagement, key, value)
+	if err != nil {
+		return nil, err
+	}
+	c.nextStateValidators = d.Committee
+	c.designations = append(c.designations, designation{index: index + 1, committee: d.Committee})
+	return []state.NotificationEvent{{
+		ScriptHash: RoleManagement,
+		Name:       DesignationEventName,
+		Item: stackitem.NewArray([]stackitem.Item{
+			stackitem.NewBigInteger(big.NewInt(StateValidatorRole)),
+			stackitem.NewBigInteger(big.NewInt(int64(index))),
+		}),
+	}}, nil
+}
+
+// Notification is an invocation emitting the event only, e.g. malformed
+// one.
+type Notification struct {
+	Contract util.Uint160
+	Name     string
+	Item     *stackitem.Array
+}
+
+func (n Notification) Script() []byte {
+	return appCall(n.Contract, "notify")
+}
+
+func (n Notification) Execute(c *MainChain, index uint32, txid util.Uint256) ([]state.NotificationEvent, error) {
+	return []state.NotificationEvent{{
+		ScriptHash: n.Contract,
+		Name:       n.Name,
+		Item:       n.Item,
+	}}, nil
+}
@@ -0,0 +1,387 @@
+package fakechain
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nspcc-dev/neo-go/pkg/config/netmode"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/mpt"
+	"github.com/nspcc-dev/neo-go/pkg/core/native/noderoles"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/storage"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/io"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract/trigger"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/vmstate"
+)
+
+const (
+	Magic            = netmode.UnitTestNet
+	DefaultCommittee = 4
+)
+
+var (
+	ErrUnknownBlock     = errors.New("unknown block")
+	ErrUnknownTx        = errors.New("unknown transaction")
+	ErrUnknownStateRoot = errors.New("unknown state root")
+	ErrUnknownProof     = errors.New("unknown proof")
+	ErrUnknownState     = errors.New("unknown state")
+	ErrUnknownContract  = errors.New("unknown contract")
+)
+
+// MainChain is an in-memory Neo N3 chain. Blocks are either added as they are
+// or persisted from invocations, in which case headers and state roots are
+// signed and storage is kept in MPT for proofs.
+type MainChain struct {
+	mtx             sync.RWMutex
+	blocks          []*block.Block
+	heights         map[util.Uint256]uint32
+	applicationLogs map[util.Uint256]*result.ApplicationLog
+	stateroots      map[uint32]*state.MPTRoot
+	proofs          map[string][]byte
+
+	contracts           map[util.Uint160]int32
+	trie                *mpt.Trie
+	store               *storage.MemCachedStore
+	nonce               uint32
+	validators          *Committee
+	nextValidators      *Committee
+	stateValidators     *Committee
+	nextStateValidators *Committee
+	designations        []designation
+	stateRootInterval   uint32
+	subscriptions       []chan struct{}
+}
+
+// designation is state validators designated from index.
+type designation struct {
+	index     uint32
+	committee *Committee
+}
+
+func NewMainChain() *MainChain {
+	validators := NewCommittee(DefaultCommittee)
+	store := storage.NewMemCachedStore(storage.NewMemoryStore())
+	return &MainChain{
+		heights:           make(map[util.Uint256]uint32),
+		applicationLogs:   make(map[util.Uint256]*result.ApplicationLog),
+		stateroots:        make(map[uint32]*state.MPTRoot),
+		proofs:            make(map[string][]byte),
+		contracts:         map[util.Uint160]int32{RoleManagement: RoleManagementId},
+		trie:              mpt.NewTrie(nil, mpt.ModeAll, store),
+		store:             store,
+		validators:        validators,
+		nextValidators:    validators,
+		stateValidators:   validators,
+		designations:      []designation{{committee: validators}},
+		stateRootInterval: 1,
+	}
+}
+
+// AddBlock appends block to the chain together with application logs of its
+// transactions.
+func (c *MainChain) AddBlock(b *block.Block, logs ...*result.ApplicationLog) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	b.Index = uint32(len(c.blocks))
+	c.blocks = append(c.blocks, b)
+	for _, tx := range b.Transactions {
+		c.heights[tx.Hash()] = b.Index
+	}
+	for _, l := range logs {
+		c.applicationLogs[l.Container] = l
+	}
+	c.notify()
+}
+
+func (c *MainChain) AddStateRoot(root *state.MPTRoot) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stateroots[root.Index] = root
+}
+
+func (c *MainChain) AddProof(rootHash util.Uint256, contractHash util.Uint160, key []byte, proof []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.proofs[proofKey(rootHash, contractHash, key)] = proof
+}
+
+func proofKey(rootHash util.Uint256, contractHash util.Uint160, key []byte) string {
+	return string(rootHash.BytesBE()) + string(contractHash.BytesBE()) + string(key)
+}
+
+// AddContract registers contract id used in storage keys.
+func (c *MainChain) AddContract(hash util.Uint160, id int32) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.contracts[hash] = id
+}
+
+// Validators returns the committee signing the next persisted block.
+func (c *MainChain) Validators() *Committee {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.validators
+}
+
+// SetNextValidators changes NextConsensus of the next persisted block, so
+// that it's a joint header and the following blocks are signed by v.
+func (c *MainChain) SetNextValidators(v *Committee) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.nextValidators = v
+}
+
+// StateValidators returns the committee signing state roots.
+func (c *MainChain) StateValidators() *Committee {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.stateValidators
+}
+
+// SetStateRootInterval makes only state roots of every interval blocks
+// signed, the others are unverified.
+func (c *MainChain) SetStateRootInterval(interval uint32) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stateRootInterval = interval
+}
+
+// Persist creates a block with a transaction per invocation and its state root.
+func (c *MainChain) Persist(invocations ...Invocation) (*block.Block, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	index := uint32(len(c.blocks))
+	b := &block.Block{
+		Header: block.Header{
+			Timestamp:     uint64(index) * 15000,
+			Index:         index,
+			NextConsensus: c.nextValidators.ScriptHash(),
+		},
+	}
+	if index > 0 {
+		b.PrevHash = c.blocks[index-1].Hash()
+	}
+	logs := make([]*result.ApplicationLog, 0, len(invocations))
+	for _, invocation := range invocations {
+		c.nonce++
+		tx := transaction.New(invocation.Script(), 0)
+		tx.Nonce = c.nonce
+		tx.ValidUntilBlock = index + 1
+		tx.Scripts = []transaction.Witness{{}}
+		events, err := invocation.Execute(c, index, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("can't execute tx %d: %w", len(b.Transactions), err)
+		}
+		b.Transactions = append(b.Transactions, tx)
+		logs = append(logs, &result.ApplicationLog{
+			Container:     tx.Hash(),
+			IsTransaction: true,
+			Executions: []state.Execution{{
+				Trigger: trigger.Application,
+				VMState: vmstate.Halt,
+				Events:  events,
+			}},
+		})
+	}
+	b.RebuildMerkleRoot()
+	b.Script = c.validators.Sign(uint32(Magic), &b.Header)
+	c.trie.Flush(index)
+	root := &state.MPTRoot{
+		Index:   index,
+		Root:    c.trie.StateRoot(),
+		Witness: []transaction.Witness{},
+	}
+	if index%c.stateRootInterval == 0 {
+		root.Witness = append(root.Witness, c.stateValidators.Sign(uint32(Magic), root))
+	}
+	c.stateroots[index] = root
+	c.blocks = append(c.blocks, b)
+	for _, l := range logs {
+		c.heights[l.Container] = index
+		c.applicationLogs[l.Container] = l
+	}
+	c.validators = c.nextValidators
+	if c.nextStateValidators != nil {
+		c.stateValidators, c.nextStateValidators = c.nextStateValidators, nil
+	}
+	c.notify()
+	return b, nil
+}
+
+// Subscribe signals every added block, notifications are not filtered since
+// they come with blocks anyway. The subscription is closed once ctx is done.
+func (c *MainChain) Subscribe(ctx context.Context, filters []neorpc.NotificationFilter) (<-chan struct{}, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	ch := make(chan struct{}, 1)
+	c.subscriptions = append(c.subscriptions, ch)
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			c.unsubscribe(ch)
+		}()
+	}
+	return ch, nil
+}
+
+func (c *MainChain) unsubscribe(ch chan struct{}) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i, s := range c.subscriptions {
+		if s == ch {
+			close(ch)
+			c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// DropSubscriptions closes subscriptions like a dropped socket does.
+func (c *MainChain) DropSubscriptions() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, ch := range c.subscriptions {
+		close(ch)
+	}
+	c.subscriptions = nil
+}
+
+func (c *MainChain) notify() {
+	for _, ch := range c.subscriptions {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *MainChain) putStorage(contract util.Uint160, key []byte, value []byte) error {
+	skey, err := c.storageKey(contract, key)
+	if err != nil {
+		return err
+	}
+	return c.trie.Put(skey, value)
+}
+
+func (c *MainChain) storageKey(contract util.Uint160, key []byte) ([]byte, error) {
+	id, ok := c.contracts[contract]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContract, contract.StringLE())
+	}
+	skey := make([]byte, 4, 4+len(key))
+	binary.LittleEndian.PutUint32(skey, uint32(id))
+	return append(skey, key...), nil
+}
+
+// GetContractStateByHash returns contract added with its id.
+func (c *MainChain) GetContractStateByHash(ctx context.Context, hash util.Uint160) (*state.Contract, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	id, ok := c.contracts[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContract, hash.StringLE())
+	}
+	return &state.Contract{ContractBase: state.ContractBase{ID: id, Hash: hash}}, nil
+}
+
+func (c *MainChain) GetBlock(ctx context.Context, index uint32) (*block.Block, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if int(index) >= len(c.blocks) {
+		return nil, ErrUnknownBlock
+	}
+	return c.blocks[index], nil
+}
+
+func (c *MainChain) GetBlockCount(ctx context.Context) (uint32, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return uint32(len(c.blocks)), nil
+}
+
+func (c *MainChain) GetVersion(ctx context.Context) (*result.Version, error) {
+	return &result.Version{
+		Protocol: result.Protocol{Network: Magic},
+	}, nil
+}
+
+// GetDesignatedByRole returns state validators designated at index, other
+// roles are not designated.
+func (c *MainChain) GetDesignatedByRole(ctx context.Context, role noderoles.Role, index uint32) (keys.PublicKeys, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if role != noderoles.StateValidator {
+		return keys.PublicKeys{}, nil
+	}
+	for i := len(c.designations) - 1; i >= 0; i-- {
+		if c.designations[i].index <= index {
+			return c.designations[i].committee.PublicKeys(), nil
+		}
+	}
+	return keys.PublicKeys{}, nil
+}
+
+func (c *MainChain) GetApplicationLog(ctx context.Context, txid util.Uint256) (*result.ApplicationLog, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	l, ok := c.applicationLogs[txid]
+	if !ok {
+		return nil, ErrUnknownTx
+	}
+	return l, nil
+}
+
+func (c *MainChain) GetTransactionHeight(ctx context.Context, txid util.Uint256) (uint32, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	h, ok := c.heights[txid]
+	if !ok {
+		return 0, ErrUnknownTx
+	}
+	return h, nil
+}
+
+func (c *MainChain) GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	root, ok := c.stateroots[index]
+	if !ok {
+		return nil, ErrUnknownStateRoot
+	}
+	return root, nil
+}
+
+// GetProof returns proof added explicitly or generated from storage of
+// persisted blocks.
+func (c *MainChain) GetProof(ctx context.Context, rootHash util.Uint256, contractHash util.Uint160, key []byte) ([]byte, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	proof, ok := c.proofs[proofKey(rootHash, contractHash, key)]
+	if ok {
+		return proof, nil
+	}
+	skey, err := c.storageKey(contractHash, key)
+	if err != nil {
+		return nil, err
+	}
+	tr := mpt.NewTrie(mpt.NewHashNode(rootHash), mpt.ModeAll, storage.NewMemCachedStore(c.store))
+	proofs, err := tr.GetProof(skey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProof, err)
+	}
+	w := io.NewBufBinWriter()
+	(&result.ProofWithKey{Key: skey, Proof: proofs}).EncodeBinary(w.BinWriter)
+	if w.Err != nil {
+		return nil, w.Err
+	}
+	return w.Bytes(), nil
+}
@@ -0,0 +1,424 @@
+package fakechain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/config"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/native"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/transaction"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/bigint"
+)
+
+const (
+	DefaultChainId = 53
+	DefaultGas     = 21000
+)
+
+// SideChain is an in-memory neo-go-evm chain, transactions sent to it are
+// mined immediately. Calls of Bridge relaying main chain objects are
+// executed, so sync results can be checked and repeated syncs fail.
+type SideChain struct {
+	mtx         sync.RWMutex
+	contracts   *native.Contracts
+	bridge      *bridge
+	chainId     uint64
+	gasPrice    *big.Int
+	baseFee     *big.Int
+	estimateGas func(tx *result.TransactionObject) (uint64, error)
+	txs         []*types.Transaction
+	pool        []*types.Transaction
+	manual      bool
+	minGasPrice *big.Int
+	txIndex     map[common.Hash]*types.Transaction
+	nonces      map[common.Address]uint64
+	balances    map[common.Address]*big.Int
+	blocks      []*block.Block
+	stateroots  map[uint32]*state.MPTRoot
+	states      map[string][]byte
+	proofs      map[string][]byte
+	receipts    map[common.Hash]*types.Receipt
+}
+
+func NewSideChain() *SideChain {
+	contracts := native.NewContracts(config.ProtocolConfiguration{})
+	return &SideChain{
+		contracts:  contracts,
+		bridge:     newBridge(&contracts.Bridge.NativeContract),
+		chainId:    DefaultChainId,
+		gasPrice:   big.NewInt(1),
+		baseFee:    big.NewInt(0),
+		txIndex:    make(map[common.Hash]*types.Transaction),
+		nonces:     make(map[common.Address]uint64),
+		balances:   make(map[common.Address]*big.Int),
+		stateroots: make(map[uint32]*state.MPTRoot),
+		states:     make(map[string][]byte),
+		proofs:     make(map[string][]byte),
+		receipts:   make(map[common.Hash]*types.Receipt),
+	}
+}
+
+// Bridge returns side chain bridge native contract.
+func (c *SideChain) Bridge() *state.NativeContract {
+	return &c.contracts.Bridge.NativeContract
+}
+
+// SetEstimateGas replaces gas estimation which executes Bridge calls by
+// default, it's where contract call failures are reported.
+func (c *SideChain) SetEstimateGas(f func(tx *result.TransactionObject) (uint64, error)) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.estimateGas = f
+}
+
+// Transactions returns all transactions sent to the chain.
+func (c *SideChain) Transactions() []*types.Transaction {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return append([]*types.Transaction{}, c.txs...)
+}
+
+func (c *SideChain) AddBlock(b *block.Block, receipts ...*types.Receipt) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	b.Index = uint32(len(c.blocks))
+	c.blocks = append(c.blocks, b)
+	for _, r := range receipts {
+		c.receipts[r.TxHash] = r
+	}
+}
+
+func (c *SideChain) AddStateRoot(root *state.MPTRoot) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stateroots[root.Index] = root
+}
+
+func (c *SideChain) AddState(rootHash common.Hash, address common.Address, key []byte, value []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.states[sideKey(rootHash, address, key)] = value
+}
+
+func (c *SideChain) AddProof(rootHash common.Hash, address common.Address, key []byte, proof []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.proofs[sideKey(rootHash, address, key)] = proof
+}
+
+func sideKey(rootHash common.Hash, address common.Address, key []byte) string {
+	return string(rootHash[:]) + string(address[:]) + string(key)
+}
+
+func (c *SideChain) Eth_NativeContract(ctx context.Context, name string) (*state.NativeContract, error) {
+	return c.contracts.ByName(name), nil
+}
+
+func (c *SideChain) Eth_ChainId(ctx context.Context) (uint64, error) {
+	return c.chainId, nil
+}
+
+func (c *SideChain) Eth_GasPrice(ctx context.Context) (*big.Int, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return new(big.Int).Set(c.gasPrice), nil
+}
+
+// SetBaseFee makes the chain charge base fee, transactions with lower max
+// fee are rejected.
+func (c *SideChain) SetBaseFee(fee *big.Int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.baseFee = fee
+}
+
+func (c *SideChain) Eth_BaseFee(ctx context.Context) (*big.Int, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return new(big.Int).Set(c.baseFee), nil
+}
+
+// SetBalance changes account balance, gas used by transactions isn't charged.
+func (c *SideChain) SetBalance(address common.Address, balance *big.Int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.balances[address] = balance
+}
+
+func (c *SideChain) Eth_GetBalance(ctx context.Context, address common.Address) (*big.Int, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	b, ok := c.balances[address]
+	if !ok {
+		return big.NewInt(0), nil
+	}
+	return new(big.Int).Set(b), nil
+}
+
+func (c *SideChain) Eth_GetTransactionCount(ctx context.Context, address common.Address) (uint64, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.nonces[address], nil
+}
+
+// SetNonce changes account nonce, e.g. as if it's used by someone else.
+func (c *SideChain) SetNonce(address common.Address, nonce uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.nonces[address] = nonce
+}
+
+// SetManualMining keeps sent transactions in pool until Mine is called.
+func (c *SideChain) SetManualMining(manual bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.manual = manual
+}
+
+// SetMinGasPrice makes pooled transactions with lower gas price stuck.
+func (c *SideChain) SetMinGasPrice(price *big.Int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.minGasPrice = price
+}
+
+// Mine executes pooled transactions in a new block, the block is empty if
+// there is no pooled transaction.
+func (c *SideChain) Mine() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	txs := []*types.Transaction{}
+	stuck := []*types.Transaction{}
+	for _, tx := range c.pool {
+		if c.minGasPrice != nil && tx.GasPrice().Cmp(c.minGasPrice) < 0 {
+			stuck = append(stuck, tx)
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	c.mine(txs)
+	c.pool = stuck
+}
+
+// SyncedHeader returns main chain header synced to Bridge.
+func (c *SideChain) SyncedHeader(index uint32) *block.Header {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.bridge.headers[index]
+}
+
+// SyncedStateRoot returns main chain state root synced to Bridge.
+func (c *SideChain) SyncedStateRoot(index uint32) *state.MPTRoot {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.bridge.stateroots[index]
+}
+
+// Minted checks whether main chain deposit is minted.
+func (c *SideChain) Minted(id uint64) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.bridge.minted[string(bigint.ToBytes(new(big.Int).SetUint64(id)))]
+}
+
+// ValidatorsIndex returns the main chain block of the last synced validators.
+func (c *SideChain) ValidatorsIndex() uint32 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.bridge.validatorsIndex
+}
+
+// StateValidatorsSynced checks whether state validators designated from
+// main chain block index are synced.
+func (c *SideChain) StateValidatorsSynced(index uint32) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.bridge.stateValidators[index]
+}
+
+func (c *SideChain) Eth_EstimateGas(ctx context.Context, tx *result.TransactionObject) (uint64, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if c.estimateGas != nil {
+		return c.estimateGas(tx)
+	}
+	if tx.To != nil && *tx.To == c.bridge.contract.Address {
+		err := c.bridge.call(tx.Data, false)
+		if err != nil {
+			return 0, executionError(err)
+		}
+	}
+	return DefaultGas, nil
+}
+
+// Eth_Call reports the error of Bridge call like native contracts do.
+func (c *SideChain) Eth_Call(ctx context.Context, tx *result.TransactionObject) ([]byte, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if tx.To != nil && *tx.To == c.bridge.contract.Address {
+		err := c.bridge.call(tx.Data, false)
+		if err != nil {
+			return nil, executionError(err)
+		}
+	}
+	return []byte{}, nil
+}
+
+// executionError is the RPC error node returns when a call fails.
+func executionError(err error) error {
+	return response.NewInvalidRequestError(fmt.Sprintf("Could not executing data: %s", err), nil)
+}
+
+func (c *SideChain) Eth_SendRawTransaction(ctx context.Context, rawTx []byte) (common.Hash, error) {
+	tx := new(types.Transaction)
+	err := tx.UnmarshalBinary(rawTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(new(big.Int).SetUint64(c.chainId)), tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	// neo-go-evm accepts transactions with the nonce of the latest state only
+	if tx.Nonce() != c.nonces[from] {
+		return common.Hash{}, fmt.Errorf("invalid nonce, addr=%s, nonce=%d, expect=%d", from, tx.Nonce(), c.nonces[from])
+	}
+	if tx.GasFeeCap().Cmp(c.baseFee) < 0 {
+		return common.Hash{}, fmt.Errorf("max fee per gas less than block base fee: maxFeePerGas: %s baseFee: %s", tx.GasFeeCap(), c.baseFee)
+	}
+	replaced := -1
+	for i, ptx := range c.pool {
+		if ptx.Nonce() == tx.Nonce() && c.sender(ptx) == from {
+			if ptx.GasFeeCapCmp(tx) >= 0 || ptx.GasTipCapCmp(tx) >= 0 {
+				return common.Hash{}, errors.New("conflicts with memory pool due to nonce")
+			}
+			replaced = i
+		}
+	}
+	c.txs = append(c.txs, tx)
+	if replaced >= 0 {
+		c.pool[replaced] = tx
+	} else if c.manual {
+		c.pool = append(c.pool, tx)
+	} else {
+		c.mine([]*types.Transaction{tx})
+	}
+	return tx.Hash(), nil
+}
+
+func (c *SideChain) sender(tx *types.Transaction) common.Address {
+	from, _ := types.Sender(types.LatestSignerForChainID(new(big.Int).SetUint64(c.chainId)), tx)
+	return from
+}
+
+func (c *SideChain) mine(txs []*types.Transaction) {
+	b := &block.Block{
+		Header: block.Header{
+			Index:     uint32(len(c.blocks)),
+			Timestamp: uint64(len(c.blocks)) * 15,
+		},
+		Transactions: make([]*transaction.Transaction, len(txs)),
+	}
+	if len(c.blocks) > 0 {
+		b.PrevHash = c.blocks[len(c.blocks)-1].Hash()
+	}
+	for i, tx := range txs {
+		b.Transactions[i] = transaction.NewTx(&transaction.EthTx{Transaction: *tx})
+	}
+	b.RebuildMerkleRoot()
+	for i, tx := range txs {
+		c.txIndex[tx.Hash()] = tx
+		c.nonces[c.sender(tx)]++
+		status := types.ReceiptStatusSuccessful
+		if tx.To() != nil && *tx.To() == c.bridge.contract.Address && c.bridge.call(tx.Data(), true) != nil {
+			status = types.ReceiptStatusFailed
+		}
+		c.receipts[tx.Hash()] = &types.Receipt{
+			Status:           status,
+			TxHash:           tx.Hash(),
+			GasUsed:          tx.Gas(),
+			BlockHash:        b.Hash(),
+			BlockNumber:      big.NewInt(int64(b.Index)),
+			TransactionIndex: uint(i),
+		}
+	}
+	c.blocks = append(c.blocks, b)
+}
+
+func (c *SideChain) Eth_GetTransactionByHash(ctx context.Context, hash common.Hash) (*result.TransactionOutputRaw, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	tx, ok := c.txIndex[hash]
+	if !ok {
+		return nil, ErrUnknownTx
+	}
+	return &result.TransactionOutputRaw{
+		Transaction: *transaction.NewTx(&transaction.EthTx{Transaction: *tx}),
+	}, nil
+}
+
+func (c *SideChain) Eth_GetTransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	r, ok := c.receipts[hash]
+	if !ok {
+		return nil, ErrUnknownTx
+	}
+	return r, nil
+}
+
+func (c *SideChain) Eth_GetBlock(ctx context.Context, index uint32) (*block.Block, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if int(index) >= len(c.blocks) {
+		return nil, ErrUnknownBlock
+	}
+	return c.blocks[index], nil
+}
+
+func (c *SideChain) Eth_GetBlockCount(ctx context.Context) (uint32, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return uint32(len(c.blocks)), nil
+}
+
+func (c *SideChain) Eth_GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	root, ok := c.stateroots[index]
+	if !ok {
+		return nil, ErrUnknownStateRoot
+	}
+	return root, nil
+}
+
+func (c *SideChain) Eth_GetState(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	v, ok := c.states[sideKey(rootHash, address, key)]
+	if !ok {
+		return nil, ErrUnknownState
+	}
+	return v, nil
+}
+
+func (c *SideChain) Eth_GetProof(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	proof, ok := c.proofs[sideKey(rootHash, address, key)]
+	if !ok {
+		return nil, ErrUnknownProof
+	}
+	return proof, nil
+}
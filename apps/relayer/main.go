@@ -1,31 +1,185 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 
+	"github.com/DigitalLabs-web3/neo-evm-bridge/api"
 	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
 	"github.com/DigitalLabs-web3/neo-evm-bridge/relay"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/wallet"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/encoding/address"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	mwallet "github.com/nspcc-dev/neo-go/pkg/wallet"
 	"golang.org/x/term"
 )
 
+const usage = `usage:
+  relayer [-config config.json]                     relay continuously
+  relayer relay-block [-config config.json] <index> relay one main chain block
+  relayer relay-tx [-config config.json] <txid>     relay one main chain transaction
+`
+
 func main() {
-	cfg, err := config.Load("config.json")
+	command := "run"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := flags.String("config", "config.json", "config file path")
+	flags.Parse(args)
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		panic(fmt.Errorf("can't load config: %w", err))
 	}
+	switch command {
+	case "run":
+		if flags.NArg() != 0 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		err = run(cfg)
+		if err != nil {
+			panic(err)
+		}
+	case "relay-block":
+		if flags.NArg() != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		index, err := strconv.ParseUint(flags.Arg(0), 10, 32)
+		if err != nil {
+			panic(fmt.Errorf("invalid block index: %w", err))
+		}
+		ctx := context.Background()
+		err = newOneShotRelayer(ctx, cfg).RelayBlock(ctx, uint32(index))
+		if err != nil {
+			panic(fmt.Errorf("can't relay block %d: %w", index, err))
+		}
+	case "relay-tx":
+		if flags.NArg() != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		txid, err := util.Uint256DecodeStringLE(strings.TrimPrefix(flags.Arg(0), "0x"))
+		if err != nil {
+			panic(fmt.Errorf("invalid transaction hash: %w", err))
+		}
+		ctx := context.Background()
+		err = newOneShotRelayer(ctx, cfg).RelayTx(ctx, txid)
+		if err != nil {
+			panic(fmt.Errorf("can't relay tx %s: %w", txid, err))
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// run relays until cfg.End or a signal, the withdrawer is stopped along with
+// the relayer. Errors are returned after services and db are closed.
+func run(cfg *config.Config) error {
+	acc, err := openWallet(cfg.Wallet, cfg.Relayer)
+	if err != nil {
+		return fmt.Errorf("can't open wallet: %w", err)
+	}
+	var nacc *mwallet.Account
+	if cfg.NeoWallet != "" {
+		nacc, err = openNeoWallet(cfg.NeoWallet, cfg.NeoRelayer)
+		if err != nil {
+			return fmt.Errorf("can't open neo wallet: %w", err)
+		}
+	}
+	db, err := store.Open(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("can't open db: %w", err)
+	}
+	defer db.Close()
+	// signals are handled after passwords are read, so that they can be
+	// interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// the next signal kills
+		stop()
+		log.Printf("shutting down, waiting for transactions in progress at most %ds\n", cfg.ShutdownTimeout)
+	}()
+	client := relay.NewClient(cfg)
+	defer client.Close()
+	relayer, err := relay.NewRelayer(ctx, cfg, acc, db, client)
+	if err != nil {
+		return fmt.Errorf("can't initialize relayer: %w", err)
+	}
+	if cfg.MetricsAddress != "" {
+		service := metrics.NewService(cfg.MetricsAddress)
+		service.Start()
+		defer service.Shutdown(context.Background())
+	}
+	if cfg.APIAddress != "" {
+		service := api.NewService(cfg.APIAddress, cfg.APIToken, relayer, db)
+		service.Start()
+		defer service.Shutdown(context.Background())
+	}
+	// relayer is stopped if withdrawer fails and vice versa
+	rctx, stopRelayer := context.WithCancel(ctx)
+	defer stopRelayer()
+	wctx, stopWithdrawer := context.WithCancel(ctx)
+	defer stopWithdrawer()
+	withdrawn := make(chan error, 1)
+	if nacc != nil {
+		withdrawer, err := relay.NewWithdrawer(ctx, cfg, nacc, db, client)
+		if err != nil {
+			return fmt.Errorf("can't initialize withdrawer: %w", err)
+		}
+		go func() {
+			err := withdrawer.Run(wctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				stopRelayer()
+			}
+			withdrawn <- err
+		}()
+	} else {
+		withdrawn <- nil
+	}
+	rerr := relayer.Run(rctx)
+	stopWithdrawer()
+	werr := <-withdrawn
+	log.Printf("relayer stopped\n")
+	if rerr != nil && !errors.Is(rerr, context.Canceled) {
+		return fmt.Errorf("relayer stopped: %w", rerr)
+	}
+	if werr != nil && !errors.Is(werr, context.Canceled) {
+		return fmt.Errorf("withdrawer stopped: %w", werr)
+	}
+	return nil
+}
+
+// newOneShotRelayer creates relayer without db, so it can work along with
+// the running relayer and doesn't touch its progress.
+func newOneShotRelayer(ctx context.Context, cfg *config.Config) *relay.Relayer {
 	acc, err := openWallet(cfg.Wallet, cfg.Relayer)
 	if err != nil {
 		panic(fmt.Errorf("can't open wallet: %w", err))
 	}
-	relayer, err := relay.NewRelayer(cfg, acc)
+	relayer, err := relay.NewRelayer(ctx, cfg, acc, nil, relay.NewClient(cfg))
 	if err != nil {
 		panic(fmt.Errorf("can't initialize relayer: %w", err))
 	}
-	relayer.Run()
+	return relayer
 }
 
 func openWallet(path string, address common.Address) (*wallet.Account, error) {
@@ -41,7 +195,7 @@ func openWallet(path string, address common.Address) (*wallet.Account, error) {
 			if acc.IsMultiSig() {
 				return nil, fmt.Errorf("unsupport multisig address relayer")
 			}
-			pass, err := readPassword(address)
+			pass, err := readPassword(address.String())
 			if err != nil {
 				return nil, fmt.Errorf("can't read password: %w", err)
 			}
@@ -55,7 +209,31 @@ func openWallet(path string, address common.Address) (*wallet.Account, error) {
 	return nil, errors.New("relayer not found in wallet")
 }
 
-func readPassword(address common.Address) (string, error) {
+func openNeoWallet(path string, addr string) (*mwallet.Account, error) {
+	h, err := address.StringToUint160(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid neo relayer address: %w", err)
+	}
+	wall, err := mwallet.NewWalletFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open wallet: %w", err)
+	}
+	acc := wall.GetAccount(h)
+	if acc == nil {
+		return nil, errors.New("neo relayer not found in wallet")
+	}
+	pass, err := readPassword(addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't read password: %w", err)
+	}
+	err = acc.Decrypt(pass, wall.Scrypt)
+	if err != nil {
+		return nil, fmt.Errorf("can't decipher account: %w", err)
+	}
+	return acc, nil
+}
+
+func readPassword(address string) (string, error) {
 	fmt.Printf("please enter passowrd for %s:\n", address)
 	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
 	if err != nil {
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "relayer"
+
+var (
+	mainHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "main_height",
+		Help:      "Main chain height",
+	})
+	relayedHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "relayed_height",
+		Help:      "The last main chain block relayed",
+	})
+	depositsSeen = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposits_seen_total",
+		Help:      "Deposit events found in main chain",
+	})
+	depositsMinted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposits_minted_total",
+		Help:      "Deposits minted in side chain",
+	})
+	headersSynced = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "headers_synced_total",
+		Help:      "Main chain headers synced to side chain",
+	})
+	stateRootsSynced = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "state_roots_synced_total",
+		Help:      "Main chain state roots synced to side chain",
+	})
+	tasksFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_failed_total",
+		Help:      "Tasks given up because of permanent errors",
+	})
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "RPC request errors per seed host",
+	}, []string{"seed"})
+	quorumMismatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quorum_mismatches_total",
+		Help:      "Quorum reads a seed host answered differently than the quorum",
+	}, []string{"seed"})
+	confirmationLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tx_confirmation_seconds",
+		Help:      "Time from sending side chain transaction to its confirmation",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600},
+	}, []string{"method"})
+	gasUsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gas_used_total",
+		Help:      "Gas used by confirmed side chain transactions",
+	}, []string{"method"})
+	balance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "account_balance",
+		Help:      "Relayer side chain account balance in wei",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		mainHeight,
+		relayedHeight,
+		depositsSeen,
+		depositsMinted,
+		headersSynced,
+		stateRootsSynced,
+		tasksFailed,
+		rpcErrors,
+		quorumMismatches,
+		confirmationLatency,
+		gasUsed,
+		balance,
+	)
+}
+
+func SetMainHeight(height uint32) {
+	mainHeight.Set(float64(height))
+}
+
+func SetRelayedHeight(index uint32) {
+	relayedHeight.Set(float64(index))
+}
+
+func AddDepositSeen() {
+	depositsSeen.Inc()
+}
+
+func AddDepositMinted() {
+	depositsMinted.Inc()
+}
+
+func AddHeaderSynced() {
+	headersSynced.Inc()
+}
+
+func AddStateRootSynced() {
+	stateRootsSynced.Inc()
+}
+
+func AddTaskFailed() {
+	tasksFailed.Inc()
+}
+
+func AddRPCError(seed string) {
+	rpcErrors.WithLabelValues(seedHost(seed)).Inc()
+}
+
+func AddQuorumMismatch(seed string) {
+	quorumMismatches.WithLabelValues(seedHost(seed)).Inc()
+}
+
+// seedHost labels seed by host, seed URLs may carry API keys in user info,
+// path or query which must not be exposed.
+func seedHost(seed string) string {
+	u, err := url.Parse(seed)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// ObserveTx records confirmed side chain transaction calling method.
+func ObserveTx(method string, latency time.Duration, gas uint64) {
+	confirmationLatency.WithLabelValues(method).Observe(latency.Seconds())
+	gasUsed.WithLabelValues(method).Add(float64(gas))
+}
+
+func SetBalance(wei *big.Int) {
+	f, _ := new(big.Float).SetInt(wei).Float64()
+	balance.Set(f)
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Service serves metrics at /metrics for Prometheus.
+type Service struct {
+	server *http.Server
+}
+
+func NewService(address string) *Service {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &Service{
+		server: &http.Server{
+			Addr:    address,
+			Handler: mux,
+		},
+	}
+}
+
+// Start serves metrics in background.
+func (s *Service) Start() {
+	log.Printf("serving metrics, address=%s\n", s.server.Addr)
+	go func() {
+		err := s.server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("metrics service stopped: %s\n", err)
+		}
+	}()
+}
+
+func (s *Service) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
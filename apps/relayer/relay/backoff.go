@@ -0,0 +1,62 @@
+package relay
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	DefaultRetryDelay = time.Second
+	MaxRetryDelay     = time.Minute
+)
+
+// backoff doubles the delay of every retry from min up to max.
+type backoff struct {
+	min   time.Duration
+	max   time.Duration
+	delay time.Duration
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{
+		min:   min,
+		max:   max,
+		delay: min,
+	}
+}
+
+// Next returns the delay before the next retry.
+func (b *backoff) Next() time.Duration {
+	d := b.delay
+	b.delay *= 2
+	if b.delay > b.max {
+		b.delay = b.max
+	}
+	return d
+}
+
+// Reset makes the next delay minimal after success.
+func (b *backoff) Reset() {
+	b.delay = b.min
+}
+
+// Wait logs transient err and waits for the next delay before retrying, the
+// wait is interrupted once ctx is done.
+func (b *backoff) Wait(ctx context.Context, err error) {
+	delay := b.Next()
+	log.Printf("%s, retry in %s\n", err, delay)
+	_ = sleep(ctx, delay)
+}
+
+// sleep waits for d, it returns ctx error once ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package relay
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/constantclient"
+	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	sstate "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	sresult "github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/native/noderoles"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	mresult "github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/actor"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// MainChain is the Neo N3 chain which deposits are relayed from.
+type MainChain interface {
+	GetBlock(ctx context.Context, index uint32) (*block.Block, error)
+	GetBlockCount(ctx context.Context) (uint32, error)
+	GetVersion(ctx context.Context) (*mresult.Version, error)
+	GetApplicationLog(ctx context.Context, txid util.Uint256) (*mresult.ApplicationLog, error)
+	GetTransactionHeight(ctx context.Context, txid util.Uint256) (uint32, error)
+	GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error)
+	GetProof(ctx context.Context, rootHash util.Uint256, contractHash util.Uint160, key []byte) ([]byte, error)
+	GetContractStateByHash(ctx context.Context, hash util.Uint160) (*state.Contract, error)
+	GetDesignatedByRole(ctx context.Context, role noderoles.Role, index uint32) (keys.PublicKeys, error)
+}
+
+// SeedSwitcher is implemented by clients which can move to another seed
+// when the current one returns invalid data.
+type SeedSwitcher interface {
+	SwitchMainSeed(ctx context.Context) error
+}
+
+// Subscriber is implemented by main chain clients which can push new blocks
+// and notifications. Signals are merged, the channel is closed when the
+// subscription drops.
+type Subscriber interface {
+	Subscribe(ctx context.Context, filters []neorpc.NotificationFilter) (<-chan struct{}, error)
+}
+
+// QuorumReader is implemented by main chain clients which can read from
+// several seeds, n seeds are asked and m of them must return the same.
+type QuorumReader interface {
+	GetApplicationLogQuorum(ctx context.Context, txid util.Uint256, n, m int) (*mresult.ApplicationLog, error)
+	GetStateRootQuorum(ctx context.Context, index uint32, n, m int) (*state.MPTRoot, error)
+}
+
+// MainActor is the main chain which withdraw transactions are sent to,
+// calls of neo-go actors are made with ctx.
+type MainActor interface {
+	MainChain
+	RPCActor(ctx context.Context) actor.RPCActor
+}
+
+// SideChain is the neo-go-evm chain which deposits are relayed to and
+// locks are relayed from.
+type SideChain interface {
+	Eth_NativeContract(ctx context.Context, name string) (*sstate.NativeContract, error)
+	Eth_ChainId(ctx context.Context) (uint64, error)
+	Eth_GasPrice(ctx context.Context) (*big.Int, error)
+	Eth_BaseFee(ctx context.Context) (*big.Int, error)
+	Eth_GetBalance(ctx context.Context, address common.Address) (*big.Int, error)
+	Eth_GetTransactionCount(ctx context.Context, address common.Address) (uint64, error)
+	Eth_EstimateGas(ctx context.Context, tx *sresult.TransactionObject) (uint64, error)
+	Eth_Call(ctx context.Context, tx *sresult.TransactionObject) ([]byte, error)
+	Eth_SendRawTransaction(ctx context.Context, rawTx []byte) (common.Hash, error)
+	Eth_GetTransactionByHash(ctx context.Context, hash common.Hash) (*sresult.TransactionOutputRaw, error)
+	Eth_GetTransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	Eth_GetBlock(ctx context.Context, index uint32) (*sblock.Block, error)
+	Eth_GetBlockCount(ctx context.Context) (uint32, error)
+	Eth_GetStateRoot(ctx context.Context, index uint32) (*sstate.MPTRoot, error)
+	Eth_GetState(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error)
+	Eth_GetProof(ctx context.Context, rootHash common.Hash, address common.Address, key []byte) ([]byte, error)
+}
+
+var (
+	_ MainActor    = (*constantclient.ConstantClient)(nil)
+	_ SideChain    = (*constantclient.ConstantClient)(nil)
+	_ SeedSwitcher = (*constantclient.ConstantClient)(nil)
+)
+
+// NewClient creates the client of cfg seeds with seed health check started.
+// It's shared by relayer and withdrawer, so that seeds are checked once, and
+// it's stopped by Close.
+func NewClient(cfg *config.Config) *constantclient.ConstantClient {
+	client := constantclient.New(cfg.MainSeeds, cfg.SideSeeds, cfg.MainWSSeeds)
+	client.StartHealthCheck(time.Duration(cfg.SeedCheckInterval)*time.Second, cfg.MaxSeedLag)
+	return client
+}
@@ -0,0 +1,69 @@
+package relay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// seeDeposit records deposit event of block, a deposit recorded already is
+// kept as is since its status is updated by the mint, so it's counted in
+// metrics once. Relaying without db doesn't record it.
+func (l *Relayer) seeDeposit(index uint32, t depositTask) error {
+	if l.store == nil {
+		return nil
+	}
+	d, err := l.store.Deposit(t.requestId)
+	if err != nil || d != nil {
+		return err
+	}
+	status := store.DepositPending
+	if t.amount < MintThreshold {
+		status = store.DepositBelowThreshold
+	}
+	now := time.Now()
+	err = l.store.PutDeposit(&store.Deposit{
+		RequestId: t.requestId,
+		MainTx:    t.txid,
+		Block:     index,
+		From:      t.from,
+		Amount:    t.amount,
+		To:        common.BytesToAddress(t.to.BytesBE()),
+		Status:    status,
+		SeenAt:    now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("can't record deposit %d: %w", t.requestId, err)
+	}
+	metrics.AddDepositSeen()
+	return nil
+}
+
+// updateDeposit sets status of deposit of task key with the reason of
+// failure, mintTx is kept if it's nil. Keys of the other tasks and of request
+// id reused by another main tx are ignored.
+func (l *Relayer) updateDeposit(key []byte, status store.DepositStatus, mintTx *common.Hash, reason string) error {
+	if l.store == nil || !isDepositKey(key) {
+		return nil
+	}
+	id := depositKeyRequestId(key)
+	d, err := l.store.Deposit(id)
+	if err != nil || d == nil || d.MainTx != keyTxId(key) {
+		return err
+	}
+	d.Status = status
+	if mintTx != nil {
+		d.MintTx = mintTx
+	}
+	d.Reason = reason
+	d.UpdatedAt = time.Now()
+	err = l.store.PutDeposit(d)
+	if err != nil {
+		return fmt.Errorf("can't update deposit %d: %w", id, err)
+	}
+	return nil
+}
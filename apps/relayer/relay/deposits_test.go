@@ -0,0 +1,48 @@
+package relay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDeposits(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 6
+	newTestChain(t, l, main)
+	require.NoError(t, l.Run(context.Background()))
+
+	mints := make(map[string]bool)
+	for _, tx := range side.Transactions() {
+		m, err := l.bridge.Abi.MethodById(tx.Data())
+		require.NoError(t, err)
+		if m.Name == CCMRequestMint {
+			mints[tx.Hash().String()] = true
+		}
+	}
+	for id, block := range map[uint64]uint32{1: 1, 3: 5} {
+		d, err := l.store.Deposit(id)
+		require.NoError(t, err)
+		require.NotNil(t, d, id)
+		assert.Equal(t, block, d.Block)
+		assert.Equal(t, store.DepositMinted, d.Status)
+		require.NotNil(t, d.MintTx)
+		assert.True(t, mints[d.MintTx.String()])
+		assert.False(t, d.SeenAt.IsZero())
+	}
+	d, err := l.store.Deposit(2)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, store.DepositBelowThreshold, d.Status)
+	assert.Nil(t, d.MintTx)
+	ds, err := l.store.DepositsBySender(d.From)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(ds))
+}
@@ -0,0 +1,126 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+var (
+	ErrTxReverted = errors.New("side tx reverted")
+	ErrTxTimeout  = errors.New("side tx unconfirmed")
+)
+
+// InvalidRequestCode is the RPC error code of side chain node failing to
+// execute a call.
+const InvalidRequestCode = -32600
+
+// TxError tells which relay transaction failed and why.
+type TxError struct {
+	// Method is the bridge method invoked.
+	Method string
+	// MainTx is the main chain transaction of the task, it's empty for
+	// header and state root sync.
+	MainTx util.Uint256
+	Tx     common.Hash
+	Reason string
+	Err    error
+}
+
+func (e *TxError) Error() string {
+	msg := fmt.Sprintf("%s %s, tx=%s", e.Method, e.Err, e.Tx)
+	if e.MainTx != (util.Uint256{}) {
+		msg += ", main tx=" + e.MainTx.StringLE()
+	}
+	if e.Reason != "" {
+		msg += ", reason: " + e.Reason
+	}
+	return msg
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// synced checks whether the transaction is reverted because its object or
+// task is synced already, e.g. by another relayer, so it's done.
+func (e *TxError) synced() bool {
+	return errors.Is(e.Err, ErrTxReverted) && isSyncedError(e.Method, e.Reason)
+}
+
+// isSyncedError checks whether bridge rejects method with msg because the
+// object or task is synced already.
+func isSyncedError(method string, msg string) bool {
+	switch {
+	case strings.Contains(msg, CCMAlreadySyncedError):
+		return true
+	case method == CCMRequestMint:
+		return strings.Contains(msg, CCMAlreadyMintedError)
+	case method == CCMSyncValidators:
+		return strings.Contains(msg, CCMValidatorsOutdatedError)
+	}
+	return false
+}
+
+// PermanentError is an error which doesn't go away on retry, e.g. malformed
+// event or contract rejection. Tasks failed with it are recorded and skipped,
+// the other errors are transient and retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent checks whether err is classified as permanent, reverted
+// transaction is permanent unless it's synced already.
+func IsPermanent(err error) bool {
+	var perr *PermanentError
+	if errors.As(err, &perr) {
+		return true
+	}
+	var terr *TxError
+	if errors.As(err, &terr) && terr.synced() {
+		return false
+	}
+	return errors.Is(err, ErrTxReverted)
+}
+
+// rejectedErrors are the reasons bridge rejects invalid objects for. The
+// other call failures may go away on retry, e.g. header or state root isn't
+// synced yet on the node lagging behind.
+var rejectedErrors = []string{
+	CCMInvalidDepositedStateError,
+	CCMDepositedStateUnmatchError,
+	CCMUnreachThresholdError,
+	CCMInvalidValidatorsStateError,
+	CCMNotDesignationProofError,
+}
+
+// isRejected checks whether side chain node fails to execute the call because
+// bridge rejects the invalid object.
+func isRejected(err error) bool {
+	var rerr *response.Error
+	if !errors.As(err, &rerr) || rerr.Code != InvalidRequestCode {
+		return false
+	}
+	for _, reason := range rejectedErrors {
+		if strings.Contains(rerr.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPermanent(t *testing.T) {
+	assert.False(t, IsPermanent(errors.New("connection refused")))
+	assert.False(t, IsPermanent(&TxError{Err: ErrTxTimeout}))
+	assert.True(t, IsPermanent(&TxError{Err: ErrTxReverted}))
+	assert.True(t, IsPermanent(&TxError{Method: CCMSyncHeader, Err: ErrTxReverted, Reason: CCMAlreadyMintedError}))
+	assert.False(t, IsPermanent(&TxError{Method: CCMSyncHeader, Err: ErrTxReverted, Reason: "Could not executing data: " + CCMAlreadySyncedError}))
+	assert.False(t, IsPermanent(fmt.Errorf("can't sync: %w", &TxError{Method: CCMRequestMint, Err: ErrTxReverted, Reason: CCMAlreadyMintedError})))
+	assert.True(t, IsPermanent(fmt.Errorf("can't sync: %w", permanent(errors.New("malformed")))))
+
+	assert.True(t, isRejected(fmt.Errorf("estimate: %w", response.NewInvalidRequestError("Could not executing data: invalid deposited state", nil))))
+	assert.True(t, isRejected(response.NewInvalidRequestError("Could not executing data: mint amount unreach threshold", nil)))
+	assert.False(t, isRejected(response.NewInvalidRequestError("Could not executing data: header not found", nil)))
+	assert.False(t, isRejected(response.NewInvalidRequestError("Could not executing data: state root not found", nil)))
+	assert.False(t, isRejected(response.NewInternalServerError("Could not get current block", nil)))
+	assert.False(t, isRejected(errors.New("connection refused")))
+}
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 3*time.Second)
+	assert.Equal(t, time.Second, b.Next())
+	assert.Equal(t, 2*time.Second, b.Next())
+	assert.Equal(t, 3*time.Second, b.Next())
+	assert.Equal(t, 3*time.Second, b.Next())
+	b.Reset()
+	assert.Equal(t, time.Second, b.Next())
+}
@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// txFee is the fee of side chain transaction. Legacy transaction pays feeCap
+// as gas price, tip is set for dynamic fee transaction only.
+type txFee struct {
+	feeCap *big.Int
+	tip    *big.Int
+}
+
+func feeOf(tx *types.Transaction) txFee {
+	if tx.Type() == types.DynamicFeeTxType {
+		return txFee{feeCap: tx.GasFeeCap(), tip: tx.GasTipCap()}
+	}
+	return txFee{feeCap: tx.GasPrice()}
+}
+
+// suggestFee returns the fee of a new transaction. Dynamic fee transaction is
+// used if cfg.TxType requires it, or it's auto and side chain has base fee.
+func (l *Relayer) suggestFee(ctx context.Context) (txFee, error) {
+	if l.cfg.TxType != config.DynamicFeeTxType && l.cfg.TxType != config.AutoTxType {
+		return l.legacyFee(ctx)
+	}
+	baseFee, err := l.side.Eth_BaseFee(ctx)
+	if err != nil {
+		if l.cfg.TxType == config.DynamicFeeTxType {
+			return txFee{}, fmt.Errorf("can't get base fee: %w", err)
+		}
+		log.Printf("can't get base fee, use legacy tx: %s\n", err)
+		return l.legacyFee(ctx)
+	}
+	if l.cfg.TxType == config.AutoTxType && baseFee.Sign() == 0 {
+		return l.legacyFee(ctx)
+	}
+	tip := l.cfg.MaxPriorityFeePerGas
+	if tip == nil {
+		// gas price suggested by node is base fee plus tip
+		price, err := l.side.Eth_GasPrice(ctx)
+		if err != nil {
+			return txFee{}, fmt.Errorf("can't get gas price: %w", err)
+		}
+		tip = new(big.Int).Sub(price, baseFee)
+		if tip.Sign() < 0 {
+			tip.SetInt64(0)
+		}
+	}
+	// leave room for base fee to double before the transaction is mined
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(2))
+	feeCap.Add(feeCap, tip)
+	if l.cfg.MaxFeePerGas != nil && feeCap.Cmp(l.cfg.MaxFeePerGas) > 0 {
+		feeCap.Set(l.cfg.MaxFeePerGas)
+	}
+	if feeCap.Cmp(baseFee) < 0 {
+		return txFee{}, fmt.Errorf("max fee per gas %s is less than base fee %s", feeCap, baseFee)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = feeCap
+	}
+	return txFee{feeCap: feeCap, tip: new(big.Int).Set(tip)}, nil
+}
+
+// legacyFee returns gas price suggested by side chain.
+func (l *Relayer) legacyFee(ctx context.Context) (txFee, error) {
+	price, err := l.side.Eth_GasPrice(ctx)
+	if err != nil {
+		return txFee{}, fmt.Errorf("can't get gas price: %w", err)
+	}
+	return txFee{feeCap: price}, nil
+}
+
+// bump increases fee by cfg.GasPriceBump percent, fee cap is limited by
+// cfg.MaxGasPrice. It returns false if fee cap can't be increased.
+func (l *Relayer) bump(fee txFee) (txFee, bool) {
+	if l.cfg.MaxGasPrice == nil {
+		return fee, false
+	}
+	feeCap := l.bumpPrice(fee.feeCap)
+	if feeCap.Cmp(l.cfg.MaxGasPrice) > 0 {
+		feeCap.Set(l.cfg.MaxGasPrice)
+	}
+	if feeCap.Cmp(fee.feeCap) <= 0 {
+		return fee, false
+	}
+	bumped := txFee{feeCap: feeCap}
+	if fee.tip != nil {
+		bumped.tip = l.bumpPrice(fee.tip)
+		if bumped.tip.Cmp(feeCap) > 0 {
+			bumped.tip.Set(feeCap)
+		}
+	}
+	return bumped, true
+}
+
+func (l *Relayer) bumpPrice(price *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(price, big.NewInt(int64(100+l.cfg.GasPriceBump)))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	return bumped
+}
+
+// txData copies unsigned tx with another nonce and fee.
+func (l *Relayer) txData(tx *types.Transaction, nonce uint64, fee txFee) types.TxData {
+	if fee.tip == nil {
+		return &types.LegacyTx{
+			Nonce:    nonce,
+			To:       tx.To(),
+			GasPrice: fee.feeCap,
+			Gas:      tx.Gas(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}
+	}
+	return &types.DynamicFeeTx{
+		ChainID:   new(big.Int).SetUint64(l.chainId),
+		Nonce:     nonce,
+		GasTipCap: fee.tip,
+		GasFeeCap: fee.feeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	}
+}
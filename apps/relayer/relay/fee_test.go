@@ -0,0 +1,122 @@
+package relay
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestFee(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+
+	l.cfg.TxType = config.LegacyTxType
+	side.SetBaseFee(big.NewInt(5))
+	fee, err := l.suggestFee(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, txFee{feeCap: big.NewInt(1)}, fee)
+
+	l.cfg.TxType = config.AutoTxType
+	side.SetBaseFee(big.NewInt(0))
+	fee, err = l.suggestFee(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, fee.tip)
+
+	side.SetBaseFee(big.NewInt(5))
+	fee, err = l.suggestFee(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, txFee{feeCap: big.NewInt(10), tip: big.NewInt(0)}, fee)
+
+	l.cfg.TxType = config.DynamicFeeTxType
+	l.cfg.MaxPriorityFeePerGas = big.NewInt(3)
+	fee, err = l.suggestFee(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, txFee{feeCap: big.NewInt(13), tip: big.NewInt(3)}, fee)
+
+	l.cfg.MaxFeePerGas = big.NewInt(6)
+	fee, err = l.suggestFee(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, txFee{feeCap: big.NewInt(6), tip: big.NewInt(3)}, fee)
+
+	l.cfg.MaxFeePerGas = big.NewInt(4)
+	_, err = l.suggestFee(context.Background())
+	require.Error(t, err)
+}
+
+func TestBump(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.GasPriceBump = 10
+
+	_, ok := l.bump(txFee{feeCap: big.NewInt(100), tip: big.NewInt(10)})
+	assert.False(t, ok)
+
+	l.cfg.MaxGasPrice = big.NewInt(105)
+	fee, ok := l.bump(txFee{feeCap: big.NewInt(100), tip: big.NewInt(100)})
+	require.True(t, ok)
+	assert.Equal(t, txFee{feeCap: big.NewInt(105), tip: big.NewInt(105)}, fee)
+
+	fee, ok = l.bump(txFee{feeCap: big.NewInt(100), tip: big.NewInt(0)})
+	require.True(t, ok)
+	assert.Equal(t, txFee{feeCap: big.NewInt(105), tip: big.NewInt(1)}, fee)
+
+	_, ok = l.bump(txFee{feeCap: big.NewInt(105)})
+	assert.False(t, ok)
+}
+
+func TestSyncDynamicFee(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.TxType = config.AutoTxType
+	side.SetBaseFee(big.NewInt(2))
+	batch := newTestBatch(t, l, main)
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+	txs := side.Transactions()
+	require.NotEmpty(t, txs)
+	for _, tx := range txs {
+		assert.Equal(t, uint8(types.DynamicFeeTxType), tx.Type())
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		require.NoError(t, err)
+		assert.Equal(t, l.account.Address, from)
+	}
+}
+
+func TestSyncReplaceDynamicFee(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.TxType = config.DynamicFeeTxType
+	l.cfg.MaxPriorityFeePerGas = big.NewInt(1)
+	l.cfg.MaxGasPrice = big.NewInt(20)
+	l.cfg.GasPriceBump = 100
+	l.cfg.ReplaceAfter = 1
+	side.SetBaseFee(big.NewInt(2))
+	batch := newTestBatch(t, l, main)
+	side.SetMinGasPrice(big.NewInt(15))
+	startMining(t, side)
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+	var caps, tips []int64
+	for _, tx := range side.Transactions() {
+		m, err := l.bridge.Abi.MethodById(tx.Data())
+		require.NoError(t, err)
+		if m.Name == CCMSyncHeader {
+			caps = append(caps, tx.GasFeeCap().Int64())
+			tips = append(tips, tx.GasTipCap().Int64())
+		}
+	}
+	assert.Equal(t, []int64{5, 10, 20}, caps)
+	assert.Equal(t, []int64{1, 2, 4}, tips)
+}
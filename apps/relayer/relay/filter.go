@@ -0,0 +1,30 @@
+package relay
+
+import (
+	"bytes"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+)
+
+const designateAsRoleMethod = "designateAsRole"
+
+// isCandidate tells whether tx may emit events relayed, so that its
+// application log is fetched. Bridge is called or receives GAS transfer if
+// its hash is pushed by the script or it signs tx, state validators are
+// designated by RoleManagement designateAsRole call. All transactions are
+// candidates in strict mode.
+func (l *Relayer) isCandidate(tx *transaction.Transaction) bool {
+	if l.cfg.StrictScan {
+		return true
+	}
+	if bytes.Contains(tx.Script, l.cfg.BridgeContract.BytesBE()) {
+		return true
+	}
+	for _, signer := range tx.Signers {
+		if signer.Account == l.cfg.BridgeContract {
+			return true
+		}
+	}
+	return bytes.Contains(tx.Script, l.roleManagementContractAddress.BytesBE()) &&
+		bytes.Contains(tx.Script, []byte(designateAsRoleMethod))
+}
@@ -0,0 +1,67 @@
+package relay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCandidate(t *testing.T) {
+	main := fakechain.NewMainChain()
+	l := newTestRelayer(t, main, fakechain.NewSideChain())
+	pk, err := keys.NewPrivateKey()
+	require.NoError(t, err)
+	other := fakechain.Notification{Contract: util.Uint160{2}, Name: "Transfer", Item: stackitem.NewArray(nil)}
+	for _, c := range []struct {
+		invocation fakechain.Invocation
+		candidate  bool
+	}{
+		{fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold}, true},
+		{fakechain.ValidatorsChange{Bridge: l.cfg.BridgeContract, Keys: keys.PublicKeys{pk.PublicKey()}}, true},
+		{fakechain.StateValidatorsDesignation{Committee: fakechain.NewCommittee(1)}, true},
+		{fakechain.Notification{Contract: fakechain.RoleManagement, Name: "Other", Item: stackitem.NewArray(nil)}, false},
+		{other, false},
+	} {
+		tx := transaction.New(c.invocation.Script(), 0)
+		assert.Equal(t, c.candidate, l.isCandidate(tx), c.invocation)
+	}
+
+	tx := transaction.New(other.Script(), 0)
+	tx.Signers = []transaction.Signer{{Account: l.cfg.BridgeContract}}
+	assert.True(t, l.isCandidate(tx))
+
+	l.cfg.StrictScan = true
+	assert.True(t, l.isCandidate(transaction.New(other.Script(), 0)))
+}
+
+func TestRunSkipUnrelatedLogs(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		main := &countingMain{MainChain: fakechain.NewMainChain()}
+		side := fakechain.NewSideChain()
+		l := newTestRelayer(t, main.MainChain, side)
+		l.main = main
+		l.store = newTestStore(t)
+		l.cfg.End = 2
+		l.cfg.StrictScan = strict
+		other := fakechain.Notification{Contract: util.Uint160{2}, Name: "Transfer", Item: stackitem.NewArray(nil)}
+		_, err := main.Persist(other)
+		require.NoError(t, err)
+		_, err = main.Persist(other, fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Run(context.Background()))
+		assert.True(t, side.Minted(1))
+		if strict {
+			assert.Equal(t, int32(3), main.logs.Load())
+		} else {
+			assert.Equal(t, int32(1), main.logs.Load())
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package relay
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/crypto/hash"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// merkleProver serves merkle proofs of the same hashes. The tree is hashed
+// once for all proofs, hash.MerkleTree is rebuilt and walked for every one.
+type merkleProver struct {
+	// levels are from leaves to root, the last node of a level is paired
+	// with itself if it has no sibling.
+	levels  [][]common.Hash
+	indexes map[common.Hash]int
+}
+
+func newMerkleProver(hashes []common.Hash) (*merkleProver, error) {
+	if len(hashes) == 0 {
+		return nil, errors.New("length of the hashes cannot be zero")
+	}
+	p := &merkleProver{indexes: make(map[common.Hash]int, len(hashes))}
+	for i, h := range hashes {
+		if _, ok := p.indexes[h]; !ok {
+			p.indexes[h] = i
+		}
+	}
+	level := hashes
+	p.levels = append(p.levels, level)
+	buf := make([]byte, 2*common.HashLength)
+	for len(level) > 1 {
+		parents := make([]common.Hash, (len(level)+1)/2)
+		for i := range parents {
+			copy(buf, level[2*i][:])
+			if 2*i+1 < len(level) {
+				copy(buf[common.HashLength:], level[2*i+1][:])
+			} else {
+				copy(buf[common.HashLength:], level[2*i][:])
+			}
+			parents[i] = hash.DoubleSha256(buf)
+		}
+		level = parents
+		p.levels = append(p.levels, level)
+	}
+	return p, nil
+}
+
+func (p *merkleProver) Root() common.Hash {
+	return p.levels[len(p.levels)-1][0]
+}
+
+// Prove returns proof of h in the format bridge contract verifies: path of
+// little endian uint32 followed by sibling hashes from leaf to root. Bit i of
+// path is set if the node at level i is a left child.
+func (p *merkleProver) Prove(h common.Hash) ([]byte, error) {
+	index, ok := p.indexes[h]
+	if !ok {
+		return nil, errors.New("target not found")
+	}
+	depth := len(p.levels) - 1
+	proof := make([]byte, 4+depth*common.HashLength)
+	path := uint32(0)
+	for i, level := range p.levels[:depth] {
+		sibling := index ^ 1
+		if index%2 == 0 {
+			path |= 1 << i
+			if sibling == len(level) {
+				sibling = index
+			}
+		}
+		copy(proof[4+i*common.HashLength:], level[sibling][:])
+		index /= 2
+	}
+	binary.LittleEndian.PutUint32(proof, path)
+	return proof, nil
+}
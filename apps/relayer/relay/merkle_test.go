@@ -0,0 +1,106 @@
+package relay
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/crypto/hash"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/vm/opcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleProver(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		hashes := make([]common.Hash, n)
+		for i := range hashes {
+			hashes[i] = hash.Sha256([]byte{byte(i)})
+		}
+		tree, err := hash.NewMerkleTree(hashes)
+		require.NoError(t, err)
+		p, err := newMerkleProver(hashes)
+		require.NoError(t, err)
+		assert.Equal(t, tree.Root(), p.Root(), n)
+		for _, h := range hashes {
+			proof, err := p.Prove(h)
+			require.NoError(t, err)
+			path := binary.LittleEndian.Uint32(proof)
+			proofs := make([]common.Hash, (len(proof)-4)/common.HashLength)
+			for i := range proofs {
+				proofs[i] = common.BytesToHash(proof[4+i*common.HashLength : 4+(i+1)*common.HashLength])
+			}
+			assert.True(t, hash.VerifyMerkleProof(tree.Root(), h, proofs, path), n)
+			expected, expectedPath, err := tree.Prove(h)
+			require.NoError(t, err)
+			assert.Equal(t, expectedPath, path, n)
+			assert.Equal(t, expected, proofs, n)
+		}
+	}
+	_, err := newMerkleProver(nil)
+	assert.Error(t, err)
+	p, err := newMerkleProver([]common.Hash{{1}})
+	require.NoError(t, err)
+	_, err = p.Prove(common.Hash{2})
+	assert.Error(t, err)
+}
+
+func TestBatchProveTx(t *testing.T) {
+	main := fakechain.NewMainChain()
+	l := newTestRelayer(t, main, fakechain.NewSideChain())
+	deposits := make([]fakechain.Invocation, 5)
+	for i := range deposits {
+		deposits[i] = fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: uint64(i), Amount: MintThreshold}
+	}
+	b, err := main.Persist(deposits...)
+	require.NoError(t, err)
+	batch := &taskBatch{block: b}
+	for _, tx := range b.Transactions {
+		proof, err := batch.proveTx(tx.Hash())
+		require.NoError(t, err)
+		tree, err := hash.NewMerkleTree(mainTxHashes(b))
+		require.NoError(t, err)
+		proofs, path, err := tree.Prove(common.BytesToHash(tx.Hash().BytesBE()))
+		require.NoError(t, err)
+		assert.Equal(t, path, binary.LittleEndian.Uint32(proof))
+		assert.Equal(t, len(proofs)*common.HashLength, len(proof)-4)
+	}
+	assert.Equal(t, common.BytesToHash(b.MerkleRoot.BytesBE()), batch.prover.Root())
+}
+
+// newBenchBlock creates a block of n transactions, each of them is a task.
+func newBenchBlock(n int) *block.Block {
+	b := new(block.Block)
+	for i := 0; i < n; i++ {
+		tx := transaction.New([]byte{byte(opcode.RET)}, 0)
+		tx.Nonce = uint32(i)
+		tx.Scripts = []transaction.Witness{{}}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	return b
+}
+
+func benchmarkProveTx(b *testing.B, n int, reuse bool) {
+	block := newBenchBlock(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch := &taskBatch{block: block}
+		for _, tx := range block.Transactions {
+			if !reuse {
+				batch = &taskBatch{block: block}
+			}
+			_, err := batch.proveTx(tx.Hash())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkProveTxRebuild100(b *testing.B)  { benchmarkProveTx(b, 100, false) }
+func BenchmarkProveTxReuse100(b *testing.B)    { benchmarkProveTx(b, 100, true) }
+func BenchmarkProveTxRebuild1000(b *testing.B) { benchmarkProveTx(b, 1000, false) }
+func BenchmarkProveTxReuse1000(b *testing.B)   { benchmarkProveTx(b, 1000, true) }
@@ -0,0 +1,58 @@
+package relay
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMetrics(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 6
+	newTestChain(t, l, main)
+	side.SetBalance(l.account.Address, big.NewInt(1000))
+
+	before := gatherCounters(t)
+	require.NoError(t, l.Run(context.Background()))
+	after := gatherCounters(t)
+	delta := func(name string) float64 { return after[name] - before[name] }
+	require.Equal(t, float64(3), delta("relayer_deposits_seen_total"))
+	require.Equal(t, float64(2), delta("relayer_deposits_minted_total"))
+	require.Equal(t, float64(5), delta("relayer_headers_synced_total"))
+	require.Equal(t, float64(3), delta("relayer_state_roots_synced_total"))
+	require.Equal(t, float64(6), after["relayer_main_height"])
+	require.Equal(t, float64(5), after["relayer_relayed_height"])
+	require.Equal(t, float64(1000), after["relayer_account_balance"])
+
+	// deposits relayed again are counted once
+	require.NoError(t, l.RelayBlock(context.Background(), 1))
+	require.Equal(t, after["relayer_deposits_seen_total"], gatherCounters(t)["relayer_deposits_seen_total"])
+}
+
+func gatherCounters(t *testing.T) map[string]float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, f := range families {
+		if !strings.HasPrefix(f.GetName(), "relayer_") {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			switch {
+			case m.Counter != nil:
+				values[f.GetName()] += m.Counter.GetValue()
+			case m.Gauge != nil:
+				values[f.GetName()] += m.Gauge.GetValue()
+			}
+		}
+	}
+	return values
+}
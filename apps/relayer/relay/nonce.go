@@ -0,0 +1,106 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	NonceTooLowError      = "nonce too low"
+	InvalidNonceError     = "invalid nonce"
+	NonceConflictError    = "conflicts with memory pool due to nonce"
+	MaxNonceRetry         = 10
+	invalidNonceExpectTag = "expect="
+)
+
+type nonceStatus int
+
+const (
+	nonceUnknown nonceStatus = iota
+	// nonceTooLow means the nonce is mined already.
+	nonceTooLow
+	// nonceTaken means the nonce is used by a pooled transaction.
+	nonceTaken
+	// nonceTooHigh means there is a gap before the nonce, side chain node
+	// doesn't pool such transactions until the gap is mined.
+	nonceTooHigh
+)
+
+// nonceManager hands out sequential nonces of relayer account, so that
+// transactions created before any of them is mined don't collide.
+type nonceManager struct {
+	mtx     sync.Mutex
+	side    SideChain
+	address common.Address
+	next    uint64
+	synced  bool
+}
+
+func newNonceManager(side SideChain, address common.Address) *nonceManager {
+	return &nonceManager{
+		side:    side,
+		address: address,
+	}
+}
+
+// Next returns nonce for a new transaction. It's never less than the
+// transaction count of node, so nonces used outside are skipped.
+func (m *nonceManager) Next(ctx context.Context) (uint64, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	count, err := m.side.Eth_GetTransactionCount(ctx, m.address)
+	if err != nil {
+		return 0, fmt.Errorf("can't get transaction count: %w", err)
+	}
+	if !m.synced || count > m.next {
+		m.next = count
+		m.synced = true
+	}
+	nonce := m.next
+	m.next++
+	return nonce, nil
+}
+
+// Reset drops handed out nonces, the next one is the transaction count of
+// node. It's used when handed out nonces are never sent.
+func (m *nonceManager) Reset() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.synced = false
+}
+
+// nonceErrorStatus checks whether err of sending transaction with nonce
+// is caused by the nonce.
+func nonceErrorStatus(err error, nonce uint64) nonceStatus {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, NonceTooLowError):
+		return nonceTooLow
+	case strings.Contains(msg, NonceConflictError):
+		return nonceTaken
+	case strings.Contains(msg, InvalidNonceError):
+		i := strings.Index(msg, invalidNonceExpectTag)
+		if i < 0 {
+			return nonceUnknown
+		}
+		expect := msg[i+len(invalidNonceExpectTag):]
+		if j := strings.IndexFunc(expect, func(r rune) bool { return r < '0' || r > '9' }); j >= 0 {
+			expect = expect[:j]
+		}
+		n, e := strconv.ParseUint(expect, 10, 64)
+		if e != nil {
+			return nonceUnknown
+		}
+		if n > nonce {
+			return nonceTooLow
+		}
+		return nonceTooHigh
+	default:
+		return nonceUnknown
+	}
+}
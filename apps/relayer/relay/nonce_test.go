@@ -0,0 +1,86 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonceErrorStatus(t *testing.T) {
+	for _, c := range []struct {
+		err    string
+		nonce  uint64
+		status nonceStatus
+	}{
+		{"nonce too low", 1, nonceTooLow},
+		{"mempool: has conflicts: conflicts with memory pool due to nonce", 1, nonceTaken},
+		{"invalid nonce, addr=0x01, nonce=1, expect=3", 1, nonceTooLow},
+		{"invalid nonce, addr=0x01, nonce=3, expect=1", 3, nonceTooHigh},
+		{"invalid nonce", 3, nonceUnknown},
+		{"insufficient funds", 1, nonceUnknown},
+	} {
+		assert.Equal(t, c.status, nonceErrorStatus(errors.New(c.err), c.nonce), c.err)
+	}
+}
+
+func TestNonceManager(t *testing.T) {
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, fakechain.NewMainChain(), side)
+	m := l.nonces
+	next := func() uint64 {
+		nonce, err := m.Next(context.Background())
+		require.NoError(t, err)
+		return nonce
+	}
+	assert.Equal(t, uint64(0), next())
+	assert.Equal(t, uint64(1), next())
+	side.SetNonce(l.account.Address, 5)
+	assert.Equal(t, uint64(5), next())
+	m.Reset()
+	assert.Equal(t, uint64(5), next())
+}
+
+func TestSyncSequentialNonces(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	startMining(t, side)
+	l := newTestRelayer(t, main, side)
+	batch := newTestBatch(t, l, main)
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+	txs := side.Transactions()
+	require.Equal(t, 3, len(txs))
+	for i, tx := range txs {
+		assert.Equal(t, uint64(i), tx.Nonce())
+	}
+}
+
+func TestSendTransactionNonceTooLow(t *testing.T) {
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, fakechain.NewMainChain(), side)
+	nonce, err := l.nonces.Next(context.Background())
+	require.NoError(t, err)
+	tx, err := l.signTransaction(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       &l.bridge.Address,
+		GasPrice: big.NewInt(1),
+		Gas:      fakechain.DefaultGas,
+		Value:    big.NewInt(0),
+	})
+	require.NoError(t, err)
+	side.SetNonce(l.account.Address, 2)
+
+	sent, ok, err := l.sendTransaction(context.Background(), tx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), sent.Nonce())
+	_, err = side.Eth_GetTransactionByHash(context.Background(), sent.Hash())
+	assert.NoError(t, err)
+}
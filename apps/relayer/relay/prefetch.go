@@ -0,0 +1,147 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// fetchedBlock is a main chain block with application logs of its
+// transactions.
+type fetchedBlock struct {
+	index uint32
+	block *block.Block
+	logs  map[util.Uint256]*result.ApplicationLog
+	err   error
+	done  chan struct{}
+}
+
+// prefetcher fetches blocks ahead of the sync cursor with a bounded number of
+// workers, blocks are handed to sync stage in index order by Get.
+type prefetcher struct {
+	main    MainChain
+	filter  func(*transaction.Transaction) bool
+	window  uint32
+	jobs    chan *fetchedBlock
+	quit    chan struct{}
+	mtx     sync.Mutex
+	fetches map[uint32]*fetchedBlock
+}
+
+// newPrefetcher starts workers fetching at most window blocks ahead,
+// application logs are fetched for transactions accepted by filter. Fetches
+// are made with ctx.
+func newPrefetcher(ctx context.Context, main MainChain, workers int, window uint32, filter func(*transaction.Transaction) bool) *prefetcher {
+	if window == 0 {
+		window = 1
+	}
+	p := &prefetcher{
+		main:    main,
+		filter:  filter,
+		window:  window,
+		jobs:    make(chan *fetchedBlock, window),
+		quit:    make(chan struct{}),
+		fetches: make(map[uint32]*fetchedBlock),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work(ctx)
+	}
+	return p
+}
+
+// Close stops workers, fetches in progress are dropped.
+func (p *prefetcher) Close() {
+	close(p.quit)
+}
+
+// Reset drops fetched blocks, e.g. when they are from a lying seed.
+func (p *prefetcher) Reset() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.fetches = make(map[uint32]*fetchedBlock)
+}
+
+// Get returns the block at index with application logs, blocks following it
+// in the window are scheduled for fetching. Failed fetches are retried on the
+// next Get.
+func (p *prefetcher) Get(index uint32) (*block.Block, map[util.Uint256]*result.ApplicationLog, error) {
+	p.mtx.Lock()
+	for i := range p.fetches {
+		if i < index {
+			delete(p.fetches, i)
+		}
+	}
+	var jobs []*fetchedBlock
+	for i := index; i < index+p.window; i++ {
+		if _, ok := p.fetches[i]; !ok {
+			p.fetches[i] = &fetchedBlock{index: i, done: make(chan struct{})}
+			jobs = append(jobs, p.fetches[i])
+		}
+	}
+	f := p.fetches[index]
+	p.mtx.Unlock()
+	for _, job := range jobs {
+		select {
+		case p.jobs <- job:
+		case <-p.quit:
+			return nil, nil, fmt.Errorf("prefetcher closed")
+		}
+	}
+	select {
+	case <-f.done:
+	case <-p.quit:
+		return nil, nil, fmt.Errorf("prefetcher closed")
+	}
+	return f.block, f.logs, f.err
+}
+
+func (p *prefetcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case f := <-p.jobs:
+			p.mtx.Lock()
+			wanted := p.fetches[f.index] == f
+			p.mtx.Unlock()
+			if !wanted { // behind the cursor or dropped already
+				continue
+			}
+			f.block, f.logs, f.err = fetchBlock(ctx, p.main, f.index, p.filter)
+			if f.err != nil {
+				p.mtx.Lock()
+				if p.fetches[f.index] == f {
+					delete(p.fetches, f.index)
+				}
+				p.mtx.Unlock()
+			}
+			close(f.done)
+		}
+	}
+}
+
+// fetchBlock gets the block at index and application logs of its transactions
+// accepted by filter, all are accepted if filter is nil.
+func fetchBlock(ctx context.Context, main MainChain, index uint32, filter func(*transaction.Transaction) bool) (*block.Block, map[util.Uint256]*result.ApplicationLog, error) {
+	b, err := main.GetBlock(ctx, index)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't get block %d: %w", index, err)
+	}
+	logs := make(map[util.Uint256]*result.ApplicationLog, len(b.Transactions))
+	for _, tx := range b.Transactions {
+		if filter != nil && !filter(tx) {
+			continue
+		}
+		applicationlog, err := main.GetApplicationLog(ctx, tx.Hash())
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't get application log of tx %s: %w", tx.Hash(), err)
+		}
+		logs[tx.Hash()] = applicationlog
+	}
+	return b, logs, nil
+}
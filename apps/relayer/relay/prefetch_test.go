@@ -0,0 +1,73 @@
+package relay
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMain counts application log requests.
+type countingMain struct {
+	*fakechain.MainChain
+	logs atomic.Int32
+}
+
+func (m *countingMain) GetApplicationLog(ctx context.Context, txid util.Uint256) (*result.ApplicationLog, error) {
+	m.logs.Add(1)
+	return m.MainChain.GetApplicationLog(ctx, txid)
+}
+
+func TestPrefetcher(t *testing.T) {
+	main := &countingMain{MainChain: fakechain.NewMainChain()}
+	main.AddContract(util.Uint160{1}, 1)
+	for i := 0; i < 10; i++ {
+		_, err := main.Persist(fakechain.Deposit{Bridge: util.Uint160{1}, Id: uint64(i), Amount: MintThreshold})
+		require.NoError(t, err)
+	}
+	p := newPrefetcher(context.Background(), main, 3, 4, nil)
+	defer p.Close()
+
+	for i := uint32(0); i < 10; i++ {
+		b, logs, err := p.Get(i)
+		require.NoError(t, err)
+		assert.Equal(t, i, b.Index)
+		require.Equal(t, 1, len(logs))
+		assert.NotNil(t, logs[b.Transactions[0].Hash()])
+	}
+	assert.Equal(t, int32(10), main.logs.Load())
+
+	_, _, err := p.Get(10)
+	assert.ErrorIs(t, err, fakechain.ErrUnknownBlock)
+	_, err = main.Persist()
+	require.NoError(t, err)
+	b, _, err := p.Get(10)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), b.Index)
+
+	p.Reset()
+	b, _, err = p.Get(10)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), b.Index)
+}
+
+func TestRunPrefetchedLogs(t *testing.T) {
+	main := &countingMain{MainChain: fakechain.NewMainChain()}
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main.MainChain, side)
+	l.main = main
+	l.store = newTestStore(t)
+	l.cfg.End = 6
+	newTestChain(t, l, main.MainChain)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.True(t, side.Minted(1))
+	assert.True(t, side.Minted(3))
+	// logs are fetched once, by prefetcher only
+	assert.Equal(t, int32(5), main.logs.Load())
+}
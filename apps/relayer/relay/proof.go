@@ -0,0 +1,65 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/mpt"
+	"github.com/nspcc-dev/neo-go/pkg/io"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// ErrInvalidStateProof means main chain seed returns a state proof which
+// doesn't match the verified state root, it's retried with another seed.
+var ErrInvalidStateProof = errors.New("invalid state proof")
+
+// verifyStateProof walks MPT proof against root and returns the value of
+// storage key of contract with id.
+func verifyStateProof(root util.Uint256, id int32, key []byte, proof []byte) ([]byte, error) {
+	pwk := new(result.ProofWithKey)
+	r := io.NewBinReaderFromBuf(proof)
+	pwk.DecodeBinary(r)
+	if r.Err != nil {
+		return nil, fmt.Errorf("can't decode proof: %w", r.Err)
+	}
+	skey := make([]byte, 4, 4+len(key))
+	binary.LittleEndian.PutUint32(skey, uint32(id))
+	skey = append(skey, key...)
+	if !bytes.Equal(pwk.Key, skey) {
+		return nil, fmt.Errorf("unexpected key %x, expect=%x", pwk.Key, skey)
+	}
+	value, ok := mpt.VerifyProof(root, pwk.Key, pwk.Proof)
+	if !ok {
+		return nil, fmt.Errorf("proof of key %x mismatches root %s", pwk.Key, root.StringLE())
+	}
+	return value, nil
+}
+
+// depositState is the deposit storage item of main chain bridge contract.
+type depositState struct {
+	txid   util.Uint256
+	from   util.Uint160
+	amount uint64
+	to     util.Uint160
+}
+
+func (d *depositState) DecodeBinary(r *io.BinReader) {
+	var (
+		txid [util.Uint256Size]byte
+		from [util.Uint160Size]byte
+		to   [util.Uint160Size]byte
+	)
+	r.ReadBytes(txid[:])
+	r.ReadBytes(from[:])
+	d.amount = r.ReadU64LE()
+	r.ReadBytes(to[:])
+	if r.Err != nil {
+		return
+	}
+	d.txid, _ = util.Uint256DecodeBytesBE(txid[:])
+	d.from, _ = util.Uint160DecodeBytesBE(from[:])
+	d.to, _ = util.Uint160DecodeBytesBE(to[:])
+}
@@ -0,0 +1,99 @@
+package relay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStateProof(t *testing.T) {
+	main := fakechain.NewMainChain()
+	bridge := util.Uint160{1}
+	main.AddContract(bridge, 1)
+	b, err := main.Persist(fakechain.Deposit{Bridge: bridge, Id: 1, From: util.Uint160{2}, Amount: 3, To: util.Uint160{4}})
+	require.NoError(t, err)
+	root, err := main.GetStateRoot(context.Background(), b.Index)
+	require.NoError(t, err)
+	key := []byte{DepositPrefix, 1}
+	proof, err := main.GetProof(context.Background(), root.Root, bridge, key)
+	require.NoError(t, err)
+
+	value, err := verifyStateProof(root.Root, 1, key, proof)
+	require.NoError(t, err)
+	task := depositTask{txid: b.Transactions[0].Hash(), requestId: 1, from: util.Uint160{2}, amount: 3, to: util.Uint160{4}}
+	require.NoError(t, task.verifyState(value))
+	mismatched := task
+	mismatched.amount = 4
+	assert.ErrorContains(t, mismatched.verifyState(value), "amount mismatch")
+
+	_, err = verifyStateProof(root.Root, 1, []byte{DepositPrefix, 2}, proof)
+	assert.ErrorContains(t, err, "unexpected key")
+	// the same key of another contract
+	_, err = verifyStateProof(root.Root, 2, key, proof)
+	assert.ErrorContains(t, err, "unexpected key")
+	_, err = verifyStateProof(util.Uint256{1}, 1, key, proof)
+	assert.ErrorContains(t, err, "mismatches root")
+	_, err = verifyStateProof(root.Root, 1, key, []byte{1, 2, 3})
+	assert.ErrorContains(t, err, "can't decode proof")
+}
+
+func TestSyncInvalidProof(t *testing.T) {
+	main := &lyingMain{MainChain: fakechain.NewMainChain()}
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main.MainChain, side)
+	l.main = main
+	batch := newTestBatch(t, l, main.MainChain)
+	root, err := main.GetStateRoot(context.Background(), batch.Index())
+	require.NoError(t, err)
+	main.AddProof(root.Root, l.cfg.BridgeContract, []byte{DepositPrefix, 1}, []byte{1, 2, 3})
+
+	err = l.sync(context.Background(), batch)
+	assert.ErrorIs(t, err, ErrInvalidStateProof)
+	assert.False(t, IsPermanent(err))
+	assert.Equal(t, 1, main.switched)
+	assert.False(t, side.Minted(1))
+}
+
+func TestSyncProofOfOtherContract(t *testing.T) {
+	main := &lyingMain{MainChain: fakechain.NewMainChain()}
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main.MainChain, side)
+	l.main = main
+	other := util.Uint160{2}
+	main.AddContract(other, 2)
+	b, err := main.Persist(
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+		fakechain.Deposit{Bridge: other, Id: 1, Amount: MintThreshold},
+	)
+	require.NoError(t, err)
+	batch := &taskBatch{block: b, isJoint: true}
+	batch.addTask(depositTask{txid: b.Transactions[0].Hash(), requestId: 1, amount: MintThreshold})
+	root, err := main.GetStateRoot(context.Background(), b.Index)
+	require.NoError(t, err)
+	// the same key proved in storage of another contract
+	key := []byte{DepositPrefix, 1}
+	proof, err := main.GetProof(context.Background(), root.Root, other, key)
+	require.NoError(t, err)
+	main.AddProof(root.Root, l.cfg.BridgeContract, key, proof)
+
+	err = l.sync(context.Background(), batch)
+	assert.ErrorIs(t, err, ErrInvalidStateProof)
+	assert.False(t, side.Minted(1))
+}
+
+func TestSyncDepositMismatch(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	batch := newTestBatch(t, l, main)
+	batch.tasks[0] = depositTask{txid: batch.tasks[0].TxId(), requestId: 1, amount: MintThreshold + 1}
+
+	err := l.sync(context.Background(), batch)
+	assert.True(t, IsPermanent(err))
+	assert.ErrorContains(t, err, "amount mismatch")
+	assert.False(t, side.Minted(1))
+}
@@ -0,0 +1,39 @@
+package relay
+
+import (
+	"context"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// quorumChain reads application logs and state roots, which decide mints,
+// from n main seeds and requires m of them to match, so that a compromised
+// seed can't forge deposits. Blocks are verified by consensus witness and
+// read from a single seed.
+type quorumChain struct {
+	MainChain
+	reader QuorumReader
+	n      int
+	m      int
+}
+
+func newQuorumChain(main MainChain, reader QuorumReader, n, m int) *quorumChain {
+	return &quorumChain{MainChain: main, reader: reader, n: n, m: m}
+}
+
+func (q *quorumChain) GetApplicationLog(ctx context.Context, txid util.Uint256) (*result.ApplicationLog, error) {
+	return q.reader.GetApplicationLogQuorum(ctx, txid, q.n, q.m)
+}
+
+func (q *quorumChain) GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
+	return q.reader.GetStateRootQuorum(ctx, index, q.n, q.m)
+}
+
+// SwitchMainSeed switches seed of the underlying chain.
+func (q *quorumChain) SwitchMainSeed(ctx context.Context) error {
+	if s, ok := q.MainChain.(SeedSwitcher); ok {
+		return s.SwitchMainSeed(ctx)
+	}
+	return nil
+}
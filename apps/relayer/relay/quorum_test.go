@@ -0,0 +1,57 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuorum reads from a single fake chain, the first failures log reads
+// fail like seeds disagree.
+type fakeQuorum struct {
+	main     *fakechain.MainChain
+	failures atomic.Int32
+	logs     atomic.Int32
+	roots    atomic.Int32
+}
+
+func (q *fakeQuorum) GetApplicationLogQuorum(ctx context.Context, txid util.Uint256, n, m int) (*result.ApplicationLog, error) {
+	if q.failures.Add(-1) >= 0 {
+		return nil, errors.New("no quorum")
+	}
+	q.logs.Add(1)
+	return q.main.GetApplicationLog(ctx, txid)
+}
+
+func (q *fakeQuorum) GetStateRootQuorum(ctx context.Context, index uint32, n, m int) (*state.MPTRoot, error) {
+	q.roots.Add(1)
+	return q.main.GetStateRoot(ctx, index)
+}
+
+func TestRunQuorum(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	q := &fakeQuorum{main: main}
+	q.failures.Store(2)
+	l.main = newQuorumChain(main, q, 3, 2)
+	l.store = newTestStore(t)
+	l.cfg.End = 6
+	newTestChain(t, l, main)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.True(t, side.Minted(1))
+	assert.True(t, side.Minted(3))
+	// all candidate logs and state roots are read with quorum, logs of a
+	// block are read again if one of them failed
+	assert.GreaterOrEqual(t, q.logs.Load(), int32(5))
+	assert.Less(t, int32(0), q.roots.Load())
+}
@@ -1,6 +1,7 @@
 package relay
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -8,15 +9,22 @@ import (
 	"log"
 	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
 	"github.com/DigitalLabs-web3/neo-evm-bridge/constantclient"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/crypto/keys"
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nspcc-dev/neo-go/pkg/config/netmode"
 	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/io"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
 	"github.com/nspcc-dev/neo-go/pkg/smartcontract/trigger"
 	"github.com/nspcc-dev/neo-go/pkg/util"
 	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
@@ -35,6 +43,7 @@ const (
 	StateValidatorRole                = 4
 	BlockTimeSeconds                  = 15
 	MaxStateRootGetRange              = 57600
+	MaxConfirmRetry                   = 10
 	MintThreshold                     = 100000000
 	RoleManagementContract            = "49cf4e5378ffcd4dec034fd98a174c5491e395e2"
 	BridgeContractName                = "Bridge"
@@ -44,134 +53,449 @@ const (
 	CCMSyncStateRootValidatorsAddress = "syncStateRootValidatorsAddress"
 	CCMRequestMint                    = "requestMint"
 	CCMAlreadySyncedError             = "already synced"
+	CCMAlreadyMintedError             = "already minted"
+	CCMValidatorsOutdatedError        = "synced validators outdated"
+	CCMInvalidDepositedStateError     = "invalid deposited state"
+	CCMDepositedStateUnmatchError     = "txid and deposited state unmatch"
+	CCMUnreachThresholdError          = "unreach threshold"
+	CCMInvalidValidatorsStateError    = "invalid main validators state"
+	CCMNotDesignationProofError       = "not designate validators proof"
 
 	DepositedEventName            = "OnDeposited"
 	ValidatorsDesignatedEventName = "OnValidatorsChanged"
+	DesignationEventName          = "Designation"
 )
 
 type Relayer struct {
 	cfg                           *config.Config
 	lastHeader                    *block.Header
+	magic                         netmode.Magic
+	chainId                       uint64
 	lastStateRoot                 *state.MPTRoot
+	stateValidators               *stateValidators
 	roleManagementContractAddress util.Uint160
-	client                        *constantclient.ConstantClient
-	bridge                        *sstate.NativeContract
-	account                       *wallet.Account
-	best                          bool
+	// contractIds are storage ids of main chain contracts which state is
+	// proved.
+	contractIds map[util.Uint160]int32
+	main        MainChain
+	side        SideChain
+	store       *store.Store
+	bridge      *sstate.NativeContract
+	account     *wallet.Account
+	nonces      *nonceManager
+	backoff     *backoff
+	best        bool
+	blockTime   time.Duration
+	// heightUpdated is when main height metric is updated.
+	heightUpdated time.Time
+	// subscriber pushes new main blocks in best mode, they are polled if
+	// it's nil or the subscription drops.
+	subscriber    Subscriber
+	events        <-chan struct{}
+	resubscribeAt time.Time
+	// statusMtx guards status and paused, they're used by the admin API
+	// while Run is going.
+	statusMtx sync.RWMutex
+	status    Status
+	paused    chan struct{}
+	rerelays  chan rerelay
 }
 
-func NewRelayer(cfg *config.Config, acc *wallet.Account) (*Relayer, error) {
+// NewRelayer creates a relayer of client chains, db can be nil for one-shot
+// relaying which doesn't persist progress. Chains are initialized with ctx.
+func NewRelayer(ctx context.Context, cfg *config.Config, acc *wallet.Account, db *store.Store, client *constantclient.ConstantClient) (*Relayer, error) {
+	var main MainChain = client
+	if cfg.QuorumSize > 0 {
+		main = newQuorumChain(client, client, cfg.QuorumSize, cfg.QuorumThreshold)
+	}
+	l, err := newRelayer(ctx, cfg, acc, db, main, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.MainWSSeeds) > 0 {
+		l.subscriber = client
+	}
+	return l, nil
+}
+
+func newRelayer(ctx context.Context, cfg *config.Config, acc *wallet.Account, db *store.Store, main MainChain, side SideChain) (*Relayer, error) {
 	roleManagement, err := util.Uint160DecodeStringLE(RoleManagementContract)
 	if err != nil {
 		return nil, err
 	}
-	client := constantclient.New(cfg.MainSeeds, cfg.SideSeeds)
-	bridge, err := client.Eth_NativeContract(BridgeContractName)
+	bridge, err := side.Eth_NativeContract(ctx, BridgeContractName)
 	if err != nil {
 		return nil, fmt.Errorf("can't get bridge contract %w", err)
 	}
+	version, err := main.GetVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get main chain version: %w", err)
+	}
+	chainId, err := side.Eth_ChainId(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get side chain id: %w", err)
+	}
+	contractIds := make(map[util.Uint160]int32)
+	for _, h := range []util.Uint160{cfg.BridgeContract, roleManagement} {
+		cs, err := main.GetContractStateByHash(ctx, h)
+		if err != nil {
+			return nil, fmt.Errorf("can't get main chain contract %s: %w", h.StringLE(), err)
+		}
+		contractIds[h] = cs.ID
+	}
 	return &Relayer{
 		cfg:                           cfg,
 		roleManagementContractAddress: roleManagement,
-		client:                        client,
+		contractIds:                   contractIds,
+		main:                          main,
+		side:                          side,
+		store:                         db,
+		magic:                         version.Protocol.Network,
+		chainId:                       chainId,
+		stateValidators:               newStateValidators(main),
 		bridge:                        bridge,
 		account:                       acc,
+		nonces:                        newNonceManager(side, acc.Address),
+		backoff:                       newBackoff(DefaultRetryDelay, MaxRetryDelay),
 		best:                          false,
+		blockTime:                     BlockTimeSeconds * time.Second,
+		rerelays:                      make(chan rerelay, MaxQueuedRerelays),
 	}, nil
 }
 
-func (l *Relayer) Run() {
-	for i := l.cfg.Start; l.cfg.End == 0 || i < l.cfg.End; {
-		if l.best {
-			time.Sleep(15 * time.Second)
+// Run relays main chain blocks until cfg.End. Transient errors are retried
+// with backoff while tasks failed with permanent errors are recorded and
+// skipped, so it returns on db errors or once ctx is done. Once ctx is done no
+// new block is fetched, while transactions of the block in progress are
+// waited for cfg.ShutdownTimeout and the block is recorded if they confirm.
+func (l *Relayer) Run(ctx context.Context) error {
+	start, err := l.resume()
+	if err != nil {
+		return fmt.Errorf("can't resume from db: %w", err)
+	}
+	l.stateValidators.Reset(start)
+	l.updateBalance(ctx)
+	prefetch := newPrefetcher(ctx, l.main, l.cfg.PrefetchWorkers, l.cfg.PrefetchWindow, l.isCandidate)
+	defer prefetch.Close()
+	for i := start; l.cfg.End == 0 || i < l.cfg.End; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.updateStatus(func(s *Status) { s.Index = i })
+		if err := l.serveAdmin(ctx); err != nil {
+			return err
 		}
 		log.Printf("syncing block, index=%d", i)
-		block, _ := l.client.GetBlock(i)
-		if block == nil {
-			if !l.best {
-				h, err := l.client.GetBlockCount()
-				if err != nil {
-					panic(err)
-				}
-				if i >= h {
-					l.best = true
-				}
+		var (
+			block *block.Block
+			logs  map[util.Uint256]*result.ApplicationLog
+		)
+		if l.best { // new blocks come one by one
+			block, logs, err = fetchBlock(ctx, l.main, i, l.isCandidate)
+		} else {
+			block, logs, err = prefetch.Get(i)
+		}
+		if err != nil {
+			h, e := l.main.GetBlockCount(ctx)
+			if e != nil {
+				l.retry(ctx, fmt.Errorf("can't get block count: %w", e))
+				continue
+			}
+			l.setMainHeight(h)
+			if i >= h { // wait for the next block
+				l.setBest()
+				l.subscribe(ctx)
+				_ = l.waitBlock(ctx)
+				continue
+			}
+			l.retry(ctx, err)
+			continue
+		}
+		l.updateMainHeight(ctx)
+		bctx, cancel := drainContext(ctx, time.Duration(l.cfg.ShutdownTimeout)*time.Second)
+		err = l.relayBlock(bctx, block, logs)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("block %d unfinished on shutdown: %s\n", i, err)
+				return ctx.Err()
+			}
+			if errors.Is(err, ErrInvalidBlock) {
+				prefetch.Reset()
 			}
+			l.retry(ctx, fmt.Errorf("can't sync block %d: %w", i, err))
 			continue
 		}
-		batch := new(taskBatch)
-		batch.block = block
-		batch.isJoint = l.isJointHeader(&block.Header)
-		if batch.isJoint {
-			log.Printf("joint header, index=%d, hash=%s\n", block.Index, block.Hash())
+		l.backoff.Reset()
+		err = l.store.PutBlock(&block.Header)
+		if err != nil {
+			return fmt.Errorf("can't persist block %d: %w", i, err)
 		}
-		for _, tx := range block.Transactions {
-			log.Printf("syncing tx, hash=%s\n", tx.Hash())
-			applicationlog, err := l.client.GetApplicationLog(tx.Hash())
+		l.lastHeader = &block.Header
+		l.setLastHeader(i, block.Hash())
+		l.stateValidators.Track(i + 1)
+		metrics.SetRelayedHeight(i)
+		i++
+	}
+	return nil
+}
+
+// updateMainHeight refreshes main chain height metric once a block time, so
+// that blocks relayed one after another don't request it every time.
+func (l *Relayer) updateMainHeight(ctx context.Context) {
+	if time.Since(l.heightUpdated) < l.blockTime {
+		return
+	}
+	h, err := l.main.GetBlockCount(ctx)
+	if err != nil {
+		log.Printf("can't get block count: %s\n", err)
+		return
+	}
+	l.setMainHeight(h)
+}
+
+// setMainHeight sets main chain height metric by block count.
+func (l *Relayer) setMainHeight(count uint32) {
+	l.heightUpdated = time.Now()
+	metrics.SetMainHeight(count - 1)
+}
+
+// retry records transient err in status and backs off.
+func (l *Relayer) retry(ctx context.Context, err error) {
+	l.addFailure(nil, err)
+	l.backoff.Wait(ctx, err)
+}
+
+// relayBlock syncs block, if it fails permanently, tasks of the block are
+// recorded failed so that the following blocks can be relayed.
+func (l *Relayer) relayBlock(ctx context.Context, block *block.Block, logs map[util.Uint256]*result.ApplicationLog) error {
+	batch, err := l.createBatch(ctx, block, logs, nil)
+	if err != nil {
+		return err
+	}
+	err = l.sync(ctx, batch)
+	if err != nil && IsPermanent(err) {
+		return l.failBatch(batch, err)
+	}
+	return err
+}
+
+// createBatch collects tasks of block transactions accepted by filter,
+// all transactions are accepted if filter is nil. Application logs missing in
+// prefetched logs are fetched.
+func (l *Relayer) createBatch(ctx context.Context, block *block.Block, logs map[util.Uint256]*result.ApplicationLog, filter func(util.Uint256) bool) (*taskBatch, error) {
+	prev, err := l.verifyBlock(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	batch := new(taskBatch)
+	batch.block = block
+	batch.isJoint = isJointHeader(prev, &block.Header)
+	if batch.isJoint {
+		log.Printf("joint header, index=%d, hash=%s\n", block.Index, block.Hash())
+	}
+	for _, tx := range block.Transactions {
+		if filter != nil && !filter(tx.Hash()) {
+			continue
+		}
+		if !l.isCandidate(tx) {
+			continue
+		}
+		log.Printf("syncing tx, hash=%s\n", tx.Hash())
+		applicationlog, ok := logs[tx.Hash()]
+		if !ok {
+			var err error
+			applicationlog, err = l.main.GetApplicationLog(ctx, tx.Hash())
 			if applicationlog == nil {
-				panic(fmt.Errorf("can't get application log, err: %w", err))
+				return nil, fmt.Errorf("can't get application log, err: %w", err)
 			}
-			for _, execution := range applicationlog.Executions {
-				if execution.Trigger == trigger.Application && execution.VMState == vmstate.Halt {
-					for _, nevent := range execution.Events {
-						event := &nevent
-						if l.isBridgeContract(event) {
-							if isDepositEvent(event) {
-								requestId, from, amount, to, err := l.parseDepositEvent(event)
+		}
+		for _, execution := range applicationlog.Executions {
+			if execution.Trigger == trigger.Application && execution.VMState == vmstate.Halt {
+				for _, nevent := range execution.Events {
+					event := &nevent
+					if l.isBridgeContract(event) {
+						if isDepositEvent(event) {
+							requestId, from, amount, to, err := l.parseDepositEvent(event)
+							if err != nil {
+								err = l.failTask(txKey(block.Index, tx.Hash()), permanent(err))
 								if err != nil {
-									panic(err)
-								}
-								log.Printf("deposit event, index=%d, tx=%s, id=%d, from=%s, amount=%d, to=%s\n", block.Index, tx.Hash(), requestId, from, amount, to)
-								if amount < MintThreshold {
-									log.Printf("threshold unreached, id=%d, from=%s, amount=%d, to=%s\n", requestId, from, amount, to)
-									continue
+									return nil, err
 								}
-								batch.addTask(depositTask{
-									txid:      tx.Hash(),
-									requestId: requestId,
-								})
-							} else if isDesignateValidatorsEvent(event) {
-								pks, err := l.parseDesignateValidatorsEvent(event)
+								continue
+							}
+							log.Printf("deposit event, index=%d, tx=%s, id=%d, from=%s, amount=%d, to=%s\n", block.Index, tx.Hash(), requestId, from, amount, to)
+							t := depositTask{
+								txid:      tx.Hash(),
+								requestId: requestId,
+								from:      from,
+								amount:    amount,
+								to:        to,
+							}
+							err = l.seeDeposit(block.Index, t)
+							if err != nil {
+								return nil, err
+							}
+							if amount < MintThreshold {
+								log.Printf("threshold unreached, id=%d, from=%s, amount=%d, to=%s\n", requestId, from, amount, to)
+								continue
+							}
+							batch.addTask(t)
+						} else if isDesignateValidatorsEvent(event) {
+							pks, err := l.parseDesignateValidatorsEvent(event)
+							if err != nil {
+								err = l.failTask(txKey(block.Index, tx.Hash()), permanent(err))
 								if err != nil {
-									panic(err)
+									return nil, err
 								}
-								log.Printf("validators designate event, index=%d, tx=%s, pks=%s\n", block.Index, tx.Hash(), pks)
-								batch.addTask(validatorsDesignateTask{
-									txid: tx.Hash(),
-								})
+								continue
 							}
-						} else if l.isRoleManagement(event) {
-							isStateValidatorsDesignate, index, err := l.parseStateValidatorsDesignatedEvent(event)
+							log.Printf("validators designate event, index=%d, tx=%s, pks=%s\n", block.Index, tx.Hash(), pks)
+							batch.addTask(validatorsDesignateTask{
+								txid: tx.Hash(),
+							})
+						}
+					} else if l.isRoleManagement(event) {
+						isStateValidatorsDesignate, index, err := l.parseStateValidatorsDesignatedEvent(event)
+						if err != nil {
+							err = l.failTask(txKey(block.Index, tx.Hash()), permanent(err))
 							if err != nil {
-								panic(err)
+								return nil, err
 							}
-							if isStateValidatorsDesignate {
-								log.Printf("state validators designate event, index=%d, tx=%s,index=%d\n", block.Index, tx.Hash(), index)
-								batch.addTask(stateValidatorsChangeTask{
-									txid:  tx.Hash(),
-									index: index,
-								})
+							continue
+						}
+						if isStateValidatorsDesignate {
+							log.Printf("state validators designate event, index=%d, tx=%s,index=%d\n", block.Index, tx.Hash(), index)
+							// designated validators sign state roots from the next block
+							err = l.stateValidators.Designate(ctx, index+1)
+							if err != nil {
+								return nil, err
 							}
+							batch.addTask(stateValidatorsChangeTask{
+								txid:  tx.Hash(),
+								index: index,
+							})
 						}
 					}
 				}
 			}
 		}
-		err := l.sync(batch)
-		if err != nil {
-			panic(fmt.Errorf("can't sync block %d: %w", i, err))
+	}
+	return batch, nil
+}
+
+// RelayBlock relays one main chain block without moving sync progress.
+func (l *Relayer) RelayBlock(ctx context.Context, index uint32) error {
+	block, err := l.main.GetBlock(ctx, index)
+	if err != nil {
+		return fmt.Errorf("can't get block %d: %w", index, err)
+	}
+	batch, err := l.createBatch(ctx, block, nil, nil)
+	if err != nil {
+		return err
+	}
+	return l.sync(ctx, batch)
+}
+
+// RelayTx relays tasks of one main chain transaction without moving sync progress.
+func (l *Relayer) RelayTx(ctx context.Context, txid util.Uint256) error {
+	index, err := l.main.GetTransactionHeight(ctx, txid)
+	if err != nil {
+		return fmt.Errorf("can't get transaction height, tx=%s: %w", txid, err)
+	}
+	block, err := l.main.GetBlock(ctx, index)
+	if err != nil {
+		return fmt.Errorf("can't get block %d: %w", index, err)
+	}
+	batch, err := l.createBatch(ctx, block, nil, func(h util.Uint256) bool {
+		return h == txid
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch.tasks) == 0 {
+		return fmt.Errorf("no task in tx %s", txid)
+	}
+	return l.sync(ctx, batch)
+}
+
+// resume loads progress from db and returns the index to continue syncing from.
+func (l *Relayer) resume() (uint32, error) {
+	index, ok, err := l.store.LastBlock()
+	if err != nil {
+		return 0, err
+	}
+	stateroot, err := l.store.LastStateRoot()
+	if err != nil {
+		return 0, err
+	}
+	l.lastStateRoot = stateroot
+	if stateroot != nil {
+		l.setLastStateRoot(stateroot.Index, stateroot.Root)
+	}
+	if !ok || index+1 < l.cfg.Start {
+		return l.cfg.Start, nil
+	}
+	header, err := l.store.LastHeader()
+	if err != nil {
+		return 0, err
+	}
+	l.lastHeader = header
+	if header != nil {
+		l.setLastHeader(index, header.Hash())
+	}
+	log.Printf("continue after stop, last synced block=%d", index)
+	return index + 1, nil
+}
+
+// verifyBlock checks block against the previous header before paying gas for
+// it and returns the previous header, main seed is switched if the block is
+// invalid.
+func (l *Relayer) verifyBlock(ctx context.Context, b *block.Block) (*block.Header, error) {
+	var prev *block.Header
+	if b.Index > 0 {
+		prev = l.lastHeader
+		if prev == nil || prev.Index+1 != b.Index {
+			pb, err := l.main.GetBlock(ctx, b.Index-1)
+			if err != nil {
+				return nil, fmt.Errorf("can't get block %d: %w", b.Index-1, err)
+			}
+			prev = &pb.Header
 		}
-		l.lastHeader = &block.Header
-		i++
 	}
+	err := verifyBlock(l.magic, prev, b)
+	if err != nil {
+		l.switchMainSeed(ctx)
+		return nil, fmt.Errorf("%w %d: %s", ErrInvalidBlock, b.Index, err)
+	}
+	return prev, nil
 }
 
-func (l *Relayer) isJointHeader(header *block.Header) bool {
-	if l.lastHeader == nil && header.Index > 0 {
-		block, _ := l.client.GetBlock(uint32(header.Index) - 1)
-		l.lastHeader = &block.Header
+// verifyStateRoot checks root is signed by the designated state validators,
+// main seed is switched if it's not.
+func (l *Relayer) verifyStateRoot(ctx context.Context, root *state.MPTRoot) error {
+	scriptHash, err := l.stateValidators.ScriptHash(ctx, root.Index)
+	if err != nil {
+		return err
+	}
+	err = verifyWitness(l.magic, root, root.Witness[0], scriptHash)
+	if err != nil {
+		l.switchMainSeed(ctx)
+		return fmt.Errorf("%w %d: %s", ErrInvalidStateRoot, root.Index, err)
 	}
-	return header.Index == 0 || l.lastHeader.NextConsensus != header.NextConsensus
+	return nil
+}
+
+func (l *Relayer) switchMainSeed(ctx context.Context) {
+	if s, ok := l.main.(SeedSwitcher); ok {
+		if err := s.SwitchMainSeed(ctx); err != nil {
+			log.Printf("can't switch main seed: %s\n", err)
+		}
+	}
+}
+
+func isJointHeader(prev *block.Header, header *block.Header) bool {
+	return prev == nil || prev.NextConsensus != header.NextConsensus
 }
 
 func (l *Relayer) isRoleManagement(event *state.NotificationEvent) bool {
@@ -180,11 +504,11 @@ func (l *Relayer) isRoleManagement(event *state.NotificationEvent) bool {
 }
 
 func (l *Relayer) parseStateValidatorsDesignatedEvent(event *state.NotificationEvent) (bool, uint32, error) {
-	if event.Name != "Designation" {
+	if event.Name != DesignationEventName {
 		return false, 0, nil
 	}
-	arr := event.Item.Value().([]stackitem.Item)
-	if len(arr) != 2 {
+	arr, ok := event.Item.Value().([]stackitem.Item)
+	if !ok || len(arr) != 2 {
 		return false, 0, errors.New("invalid role deposite event arguments count")
 	}
 	role, err := arr[0].TryInteger()
@@ -206,8 +530,8 @@ func isDepositEvent(event *state.NotificationEvent) bool {
 }
 
 func (l *Relayer) parseDepositEvent(event *state.NotificationEvent) (requestId uint64, from util.Uint160, amount uint64, to util.Uint160, err error) {
-	arr := event.Item.Value().([]stackitem.Item)
-	if len(arr) != 4 {
+	arr, ok := event.Item.Value().([]stackitem.Item)
+	if !ok || len(arr) != 4 {
 		err = errors.New("invalid deposited event arguments count")
 		return
 	}
@@ -233,7 +557,8 @@ func (l *Relayer) parseDepositEvent(event *state.NotificationEvent) (requestId u
 	}
 	from = bf
 	if arr[2].Type() != stackitem.IntegerT {
-		panic("invalid amount type in deposit event")
+		err = errors.New("invalid amount type in deposit event")
+		return
 	}
 	amt, err := arr[2].TryInteger()
 	if err != nil {
@@ -264,12 +589,16 @@ func isDesignateValidatorsEvent(event *state.NotificationEvent) bool {
 }
 
 func (l *Relayer) parseDesignateValidatorsEvent(event *state.NotificationEvent) (pks keys.PublicKeys, err error) {
-	arr := event.Item.Value().([]stackitem.Item)
-	if len(arr) != 1 {
+	arr, ok := event.Item.Value().([]stackitem.Item)
+	if !ok || len(arr) != 1 {
 		err = errors.New("invalid validators change arguments count")
 		return
 	}
-	arr = arr[0].Value().([]stackitem.Item)
+	arr, ok = arr[0].Value().([]stackitem.Item)
+	if !ok {
+		err = errors.New("invalid validators type in validators change event")
+		return
+	}
 	pks = make([]*keys.PublicKey, len(arr))
 	for i, p := range arr {
 		if p.Type() != stackitem.ByteArrayT {
@@ -295,46 +624,78 @@ func (l *Relayer) isBridgeContract(notification *state.NotificationEvent) bool {
 	return notification.ScriptHash == l.cfg.BridgeContract
 }
 
-func (l *Relayer) sync(batch *taskBatch) error {
-	transactions := []*types.Transaction{}
+func (l *Relayer) sync(ctx context.Context, batch *taskBatch) error {
+	err := l.syncBatch(ctx, batch)
+	if err != nil {
+		// nonces of created but unsent transactions are never used
+		l.nonces.Reset()
+	}
+	return err
+}
+
+func (l *Relayer) syncBatch(ctx context.Context, batch *taskBatch) error {
+	transactions := []relayTx{}
 	if batch.isJoint || len(batch.tasks) > 0 {
-		tx, err := l.createHeaderSyncTransaction(&batch.block.Header)
+		tx, err := l.createHeaderSyncTransaction(ctx, &batch.block.Header)
 		if err != nil {
 			return err
 		}
 		if tx != nil { //synced already
-			transactions = append(transactions, tx)
+			transactions = append(transactions, relayTx{tx: tx, method: CCMSyncHeader})
 		}
 	}
 	var stateroot *state.MPTRoot
 	if len(batch.tasks) > 0 {
-		sr, err := l.getVerifiedStateRoot(batch.Index())
+		sr, err := l.getVerifiedStateRoot(ctx, batch.Index())
 		if err != nil {
 			return err
 		}
-		tx, err := l.createStateRootSyncTransaction(sr)
+		tx, err := l.createStateRootSyncTransaction(ctx, sr)
 		if err != nil {
 			return err
 		}
 		if tx != nil { //synced already
-			transactions = append(transactions, tx)
+			transactions = append(transactions, relayTx{tx: tx, method: CCMSyncStateRoot})
 		}
 		stateroot = sr
 	}
-	err := l.commitTransactions(transactions)
+	err := l.commitTransactions(ctx, transactions)
 	if err != nil {
 		return err
 	}
+	for _, t := range transactions {
+		if t.err != nil {
+			if !t.err.synced() {
+				return t.err
+			}
+			log.Printf("%s reverted as synced already, tx=%s\n", t.method, t.err.Tx)
+		}
+	}
 	transactions = transactions[:0]
 	for _, t := range batch.tasks {
+		tkey := taskKey(batch.Index(), t)
+		status, err := l.taskStatus(tkey)
+		if err != nil {
+			return err
+		}
+		if status == store.TaskDone {
+			log.Printf("skip done task, tx=%s\n", t.TxId())
+			continue
+		}
+		if status == store.TaskFailed {
+			log.Printf("skip failed task, tx=%s\n", t.TxId())
+			continue
+		}
 		var (
 			key      []byte
 			method   string
 			contract util.Uint160 = l.cfg.BridgeContract
+			verify   func(value []byte) error
 		)
 		switch v := t.(type) {
 		case depositTask:
 			method = CCMRequestMint
+			verify = v.verifyState
 			key = append([]byte{DepositPrefix}, big.NewInt(int64(v.requestId)).Bytes()...)
 		case validatorsDesignateTask:
 			method = CCMSyncValidators
@@ -348,19 +709,119 @@ func (l *Relayer) sync(batch *taskBatch) error {
 		default:
 			return errors.New("unkown task")
 		}
-		tx, err := l.createStateSyncTransaction(method, batch.block, t.TxId(), stateroot, contract, key)
+		tx, err := l.createStateSyncTransaction(ctx, method, batch, t.TxId(), stateroot, contract, key, verify)
 		if err != nil {
-			return err
+			if !IsPermanent(err) {
+				return err
+			}
+			err = l.failTask(tkey, err)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 		if tx == nil { //synced already
+			err = l.putTaskStatus(tkey, store.TaskDone)
+			if err != nil {
+				return err
+			}
+			err = l.updateDeposit(tkey, store.DepositMinted, nil, "")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err = l.putTaskStatus(tkey, store.TaskPending)
+		if err != nil {
+			return err
+		}
+		hash := tx.Hash()
+		err = l.updateDeposit(tkey, store.DepositPending, &hash, "")
+		if err != nil {
+			return err
+		}
+		transactions = append(transactions, relayTx{tx: tx, method: method, mainTx: t.TxId(), key: tkey})
+	}
+	err = l.commitTransactions(ctx, transactions)
+	if err != nil {
+		return err
+	}
+	for _, t := range transactions {
+		if t.err != nil && t.err.synced() {
+			log.Printf("%s reverted as synced already, tx=%s\n", t.method, t.err.Tx)
+			err = l.putTaskStatus(t.key, store.TaskDone)
+			if err == nil {
+				err = l.updateDeposit(t.key, store.DepositMinted, nil, "")
+			}
+		} else if t.err != nil {
+			err = l.failTask(t.key, t.err)
+		} else {
+			err = l.putTaskStatus(t.key, store.TaskDone)
+			if err == nil {
+				err = l.updateDeposit(t.key, store.DepositMinted, &t.mined, "")
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failTask records task failed with permanent error, so it's skipped from
+// now on. Relaying without db reports err instead.
+func (l *Relayer) failTask(key []byte, err error) error {
+	if l.store == nil {
+		return err
+	}
+	log.Printf("task failed, key=%s: %s\n", hex.EncodeToString(key), err)
+	metrics.AddTaskFailed()
+	l.addFailure(key, err)
+	e := l.store.PutFailedTask(key, err.Error())
+	if e != nil {
+		return e
+	}
+	return l.updateDeposit(key, store.DepositFailed, nil, err.Error())
+}
+
+// failBatch records unfinished tasks of batch failed, the block is recorded
+// if there is no task.
+func (l *Relayer) failBatch(batch *taskBatch, err error) error {
+	if len(batch.tasks) == 0 {
+		return l.failTask(txKey(batch.Index(), util.Uint256{}), err)
+	}
+	for _, t := range batch.tasks {
+		tkey := taskKey(batch.Index(), t)
+		status, e := l.taskStatus(tkey)
+		if e != nil {
+			return e
+		}
+		if status == store.TaskDone || status == store.TaskFailed {
 			continue
 		}
-		transactions = append(transactions, tx)
+		e = l.failTask(tkey, err)
+		if e != nil {
+			return e
+		}
 	}
-	return l.commitTransactions(transactions)
+	return nil
 }
 
-func (l *Relayer) getVerifiedStateRoot(index uint32) (*state.MPTRoot, error) {
+func (l *Relayer) taskStatus(key []byte) (store.TaskStatus, error) {
+	if l.store == nil {
+		return store.TaskUnknown, nil
+	}
+	return l.store.TaskStatus(key)
+}
+
+func (l *Relayer) putTaskStatus(key []byte, status store.TaskStatus) error {
+	if l.store == nil {
+		return nil
+	}
+	return l.store.PutTaskStatus(key, status)
+}
+
+func (l *Relayer) getVerifiedStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
 	if l.lastStateRoot != nil && l.lastStateRoot.Index >= index {
 		return l.lastStateRoot, nil
 	}
@@ -369,10 +830,12 @@ func (l *Relayer) getVerifiedStateRoot(index uint32) (*state.MPTRoot, error) {
 	}
 	stateIndex := index
 	for stateIndex < index+MaxStateRootGetRange {
-		stateroot, err := l.client.GetStateRoot(stateIndex)
+		stateroot, err := l.main.GetStateRoot(ctx, stateIndex)
 		if err != nil {
 			if l.best { // wait next block, verified stateroot approved in next block
-				time.Sleep(15 * time.Second)
+				if err := l.waitBlock(ctx); err != nil {
+					return nil, err
+				}
 				continue
 			}
 			return nil, fmt.Errorf("can't get state root,  %w", err)
@@ -381,27 +844,38 @@ func (l *Relayer) getVerifiedStateRoot(index uint32) (*state.MPTRoot, error) {
 			stateIndex++
 			continue
 		}
+		err = l.verifyStateRoot(ctx, stateroot)
+		if err != nil {
+			return nil, err
+		}
 		log.Printf("verified state root found, index=%d", stateIndex)
+		if l.store != nil {
+			err = l.store.PutStateRoot(stateroot)
+			if err != nil {
+				return nil, fmt.Errorf("can't persist state root: %w", err)
+			}
+		}
 		l.lastStateRoot = stateroot
+		l.setLastStateRoot(stateroot.Index, stateroot.Root)
 		return stateroot, nil
 	}
 	return nil, errors.New("can't get verified state root, exceeds MaxStateRootGetRange")
 }
 
-func (l *Relayer) invokeObjectSync(method string, object []byte) (*types.Transaction, error) {
+func (l *Relayer) invokeObjectSync(ctx context.Context, method string, object []byte) (*types.Transaction, error) {
 	data, err := l.bridge.Abi.Pack(method, object)
 	if err != nil {
 		return nil, fmt.Errorf("can't pack sync object, method=%s: %w", method, err)
 	}
-	return l.createEthLayerTransaction(data)
+	return l.createEthLayerTransaction(ctx, data)
 }
 
-func (l *Relayer) createHeaderSyncTransaction(rpcHeader *block.Header) (*types.Transaction, error) {
+func (l *Relayer) createHeaderSyncTransaction(ctx context.Context, rpcHeader *block.Header) (*types.Transaction, error) {
 	b, err := blockHeaderToBytes(mainHeaderToSideHeader(rpcHeader))
 	if err != nil {
 		return nil, fmt.Errorf("can't encode block header: %w", err)
 	}
-	tx, err := l.invokeObjectSync(CCMSyncHeader, b)
+	tx, err := l.invokeObjectSync(ctx, CCMSyncHeader, b)
 	if err != nil {
 		if strings.Contains(err.Error(), CCMAlreadySyncedError) {
 			log.Println("skip synced header")
@@ -414,12 +888,12 @@ func (l *Relayer) createHeaderSyncTransaction(rpcHeader *block.Header) (*types.T
 	return tx, nil
 }
 
-func (l *Relayer) createStateRootSyncTransaction(stateroot *state.MPTRoot) (*types.Transaction, error) {
+func (l *Relayer) createStateRootSyncTransaction(ctx context.Context, stateroot *state.MPTRoot) (*types.Transaction, error) {
 	b, err := staterootToBytes(mainStateRootToSideStateRoot(stateroot))
 	if err != nil {
 		return nil, fmt.Errorf("can't encode stateroot: %w", err)
 	}
-	tx, err := l.invokeObjectSync(CCMSyncStateRoot, b)
+	tx, err := l.invokeObjectSync(ctx, CCMSyncStateRoot, b)
 	if err != nil {
 		if strings.Contains(err.Error(), CCMAlreadySyncedError) {
 			log.Println("skip synced state root")
@@ -432,35 +906,41 @@ func (l *Relayer) createStateRootSyncTransaction(stateroot *state.MPTRoot) (*typ
 	return tx, nil
 }
 
-func (l *Relayer) invokeStateSync(method string, index uint32, txid util.Uint256, txproof []byte, rootIndex uint32, stateproof []byte) (*types.Transaction, error) {
+func (l *Relayer) invokeStateSync(ctx context.Context, method string, index uint32, txid util.Uint256, txproof []byte, rootIndex uint32, stateproof []byte) (*types.Transaction, error) {
 	data, err := l.bridge.Abi.Pack(method, index, big.NewInt(0).SetBytes(common.BytesToHash(txid.BytesBE()).Bytes()), txproof, rootIndex, stateproof)
 	if err != nil {
 		return nil, err
 	}
-	return l.createEthLayerTransaction(data)
+	return l.createEthLayerTransaction(ctx, data)
 }
 
-func (l *Relayer) createStateSyncTransaction(method string, block *block.Block, txid util.Uint256, stateroot *state.MPTRoot, contract util.Uint160, key []byte) (*types.Transaction, error) {
-	txproof, err := proveTx(block, txid) // TODO: merkle tree reuse
+// createStateSyncTransaction proves key of contract storage to bridge, the
+// proof is verified against stateroot and the proved value is checked by
+// verify if it's not nil.
+func (l *Relayer) createStateSyncTransaction(ctx context.Context, method string, batch *taskBatch, txid util.Uint256, stateroot *state.MPTRoot, contract util.Uint160, key []byte, verify func(value []byte) error) (*types.Transaction, error) {
+	txproof, err := batch.proveTx(txid)
 	if err != nil {
 		return nil, fmt.Errorf("can't build tx proof: %w", err)
 	}
-	stateproof, err := l.client.GetProof(stateroot.Root, contract, key)
+	stateproof, err := l.main.GetProof(ctx, stateroot.Root, contract, key)
 	if err != nil {
 		return nil, fmt.Errorf("can't get state proof %w", err)
 	}
-	tx, err := l.invokeStateSync(method, uint32(block.Index), txid, txproof, stateroot.Index, stateproof)
+	value, err := verifyStateProof(stateroot.Root, l.contractIds[contract], key, stateproof)
 	if err != nil {
-		if strings.Contains(err.Error(), CCMAlreadySyncedError) {
-			log.Printf("%s skip synced\n", method)
-			return nil, nil
-		}
-		if method == CCMSyncValidators && strings.Contains(err.Error(), "synced validators outdated") {
-			log.Printf("%s skip synced validators", method)
-			return nil, nil
+		l.switchMainSeed(ctx)
+		return nil, fmt.Errorf("%w of %s: %s", ErrInvalidStateProof, method, err)
+	}
+	if verify != nil {
+		err = verify(value)
+		if err != nil {
+			return nil, permanent(fmt.Errorf("%s state of tx %s: %w", method, txid, err))
 		}
-		if method == CCMRequestMint && strings.Contains(err.Error(), "already minted") {
-			log.Printf("%s skip synced mint", method)
+	}
+	tx, err := l.invokeStateSync(ctx, method, batch.Index(), txid, txproof, stateroot.Index, stateproof)
+	if err != nil {
+		if isSyncedError(method, err.Error()) {
+			log.Printf("%s skip synced\n", method)
 			return nil, nil
 		}
 		return nil, err
@@ -469,79 +949,292 @@ func (l *Relayer) createStateSyncTransaction(method string, block *block.Block,
 	return tx, nil
 }
 
-func (l *Relayer) createEthLayerTransaction(data []byte) (*types.Transaction, error) {
-	var err error
-	chainId := l.client.Eth_ChainId()
-	gasPrice := l.client.Eth_GasPrice()
-	nonce := l.client.Eth_GetTransactionCount(l.account.Address)
+func (l *Relayer) createEthLayerTransaction(ctx context.Context, data []byte) (*types.Transaction, error) {
+	fee, err := l.suggestFee(ctx)
+	if err != nil {
+		return nil, err
+	}
 	ltx := &types.LegacyTx{
-		Nonce:    nonce,
 		To:       &(l.bridge.Address),
-		GasPrice: gasPrice,
+		GasPrice: fee.feeCap,
 		Value:    big.NewInt(0),
 		Data:     data,
 	}
-	tx := &transaction.EthTx{
-		Transaction: *types.NewTx(ltx),
-	}
-	gas, err := l.client.Eth_EstimateGas(&sresult.TransactionObject{
+	gas, err := l.side.Eth_EstimateGas(ctx, &sresult.TransactionObject{
 		From:     l.account.Address,
-		To:       tx.To(),
-		GasPrice: tx.GasPrice(),
-		Value:    tx.Value(),
-		Data:     tx.Data(),
+		To:       ltx.To,
+		GasPrice: ltx.GasPrice,
+		Value:    ltx.Value,
+		Data:     ltx.Data,
 	})
 	if err != nil {
+		if isRejected(err) {
+			return nil, permanent(err)
+		}
 		return nil, err
 	}
 	ltx.Gas = gas
-	tx.Transaction = *types.NewTx(ltx)
-	err = l.account.SignTx(chainId, transaction.NewTx(tx))
+	nonce, err := l.nonces.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return l.signTransaction(l.txData(types.NewTx(ltx), nonce, fee))
+}
+
+func (l *Relayer) signTransaction(data types.TxData) (*types.Transaction, error) {
+	if _, ok := data.(*types.LegacyTx); !ok {
+		// wallet signs with EIP155 signer which doesn't support typed transactions
+		tx, err := types.SignNewTx(&l.account.PrivateKey().PrivateKey, types.NewLondonSigner(new(big.Int).SetUint64(l.chainId)), data)
+		if err != nil {
+			return nil, fmt.Errorf("can't sign tx: %w", err)
+		}
+		return tx, nil
+	}
+	tx := &transaction.EthTx{
+		Transaction: *types.NewTx(data),
+	}
+	err := l.account.SignTx(l.chainId, transaction.NewTx(tx))
 	if err != nil {
 		return nil, fmt.Errorf("can't sign tx: %w", err)
 	}
 	return &tx.Transaction, nil
 }
 
-func (l *Relayer) commitTransactions(transactions []*types.Transaction) error {
+// resignTransaction creates the same transaction with another nonce.
+func (l *Relayer) resignTransaction(tx *types.Transaction, nonce uint64) (*types.Transaction, error) {
+	return l.signTransaction(l.txData(tx, nonce, feeOf(tx)))
+}
+
+// sendTransaction sends tx, it's resigned if the nonce is used already. It
+// returns the transaction sent, or false if there is a nonce gap and tx
+// should be sent after the former ones are mined.
+func (l *Relayer) sendTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, bool, error) {
+	for retry := 0; ; retry++ {
+		b, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, false, err
+		}
+		_, err = l.side.Eth_SendRawTransaction(ctx, b)
+		if err == nil {
+			return tx, true, nil
+		}
+		status := nonceErrorStatus(err, tx.Nonce())
+		if status == nonceUnknown || retry >= MaxNonceRetry {
+			return nil, false, err
+		}
+		if status == nonceTooHigh {
+			log.Printf("nonce gap, tx=%s, nonce=%d, wait\n", tx.Hash(), tx.Nonce())
+			return tx, false, nil
+		}
+		if status == nonceTooLow {
+			l.nonces.Reset()
+		}
+		nonce, err := l.nonces.Next(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		log.Printf("nonce used, tx=%s, nonce=%d, resign with nonce=%d\n", tx.Hash(), tx.Nonce(), nonce)
+		tx, err = l.resignTransaction(tx, nonce)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// replaceTransaction resends tx with the same nonce and higher fee, it
+// returns nil if fee reaches cfg.MaxGasPrice already.
+func (l *Relayer) replaceTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	fee, ok := l.bump(feeOf(tx))
+	if !ok {
+		return nil, nil
+	}
+	ntx, err := l.signTransaction(l.txData(tx, tx.Nonce(), fee))
+	if err != nil {
+		return nil, err
+	}
+	b, err := ntx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	_, err = l.side.Eth_SendRawTransaction(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("replaced tx=%s with tx=%s, gasPrice=%s\n", tx.Hash(), ntx.Hash(), fee.feeCap)
+	return ntx, nil
+}
+
+// relayTx is a side chain transaction relaying main chain object or task.
+type relayTx struct {
+	tx     *types.Transaction
+	method string
+	mainTx util.Uint256
+	// hashes are all sent versions of tx, the latest is the last.
+	hashes []common.Hash
+	sentAt uint32
+	// sentTime is when the first version is sent.
+	sentTime time.Time
+	// key is the task key, it's nil for header and state root sync.
+	key []byte
+	// err is set if the transaction is reverted.
+	err *TxError
+	// mined is the version of tx confirmed.
+	mined common.Hash
+}
+
+// commitTransactions sends transactions and waits until they are
+// confirmed by cfg.Confirmations blocks. A reverted transaction doesn't stop
+// waiting for the others, its err is set instead.
+// Transactions not mined in cfg.ReplaceAfter block times are replaced with
+// higher gas price, whichever version is mined confirms the transaction.
+func (l *Relayer) commitTransactions(ctx context.Context, transactions []relayTx) error {
 	if len(transactions) == 0 {
 		return nil
 	}
-	appending := make([]common.Hash, len(transactions))
-	for i, tx := range transactions {
-		b, err := tx.MarshalBinary()
+	appending := make([]int, len(transactions))
+	for i := range appending {
+		appending[i] = i
+	}
+	limit := MaxConfirmRetry + l.cfg.Confirmations + uint32(len(transactions))
+	defer l.setPending(nil, nil)
+	for retry := uint32(1); retry <= limit; retry++ {
+		// send in nonce order, the ones after a nonce gap are sent later
+		for i := range transactions {
+			t := &transactions[i]
+			if len(t.hashes) > 0 {
+				continue
+			}
+			tx, ok, err := l.sendTransaction(ctx, t.tx)
+			if err != nil {
+				return err
+			}
+			t.tx = tx
+			if !ok {
+				break
+			}
+			t.hashes = append(t.hashes, tx.Hash())
+			t.sentAt = retry
+			t.sentTime = time.Now()
+		}
+		l.setPending(transactions, appending)
+		err := sleep(ctx, l.blockTime)
 		if err != nil {
 			return err
 		}
-		h, err := l.client.Eth_SendRawTransaction(b)
+		count, err := l.side.Eth_GetBlockCount(ctx)
 		if err != nil {
-			return err
+			log.Printf("can't get side block count: %s\n", err)
+			continue
 		}
-		appending[i] = h
-	}
-	retry := 10
-	for retry > 0 {
-		time.Sleep(BlockTimeSeconds * time.Second)
-		rest := make([]common.Hash, 0, len(appending))
-		for _, h := range appending {
-			txResp := l.client.Eth_GetTransactionByHash(h)
-			if txResp == nil {
-				rest = append(rest, h)
+		rest := make([]int, 0, len(appending))
+		for _, i := range appending {
+			t := &transactions[i]
+			receipt := l.findReceipt(ctx, t.hashes)
+			if receipt == nil {
+				if len(t.hashes) > 0 && retry-t.sentAt >= l.cfg.ReplaceAfter {
+					tx, err := l.replaceTransaction(ctx, t.tx)
+					if err != nil {
+						log.Printf("can't replace tx %s: %s\n", t.tx.Hash(), err)
+					} else if tx != nil {
+						t.tx = tx
+						t.hashes = append(t.hashes, tx.Hash())
+						t.sentAt = retry
+					}
+				}
+				rest = append(rest, i)
+				continue
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.err = &TxError{
+					Method: t.method,
+					MainTx: t.mainTx,
+					Tx:     receipt.TxHash,
+					Reason: l.revertReason(ctx, t.tx),
+					Err:    ErrTxReverted,
+				}
+				continue
+			}
+			if receipt.BlockNumber.Uint64()+uint64(l.cfg.Confirmations) >= uint64(count) {
+				rest = append(rest, i)
+				continue
 			}
+			t.mined = receipt.TxHash
+			observeTx(t, receipt)
 		}
 		if len(rest) == 0 {
+			l.updateBalance(ctx)
 			return nil
 		}
 		appending = rest
-		retry--
 	}
-	return fmt.Errorf("can't commit transactions: %v", appending)
+	t := transactions[appending[0]]
+	return &TxError{
+		Method: t.method,
+		MainTx: t.mainTx,
+		Tx:     t.tx.Hash(),
+		Err:    ErrTxTimeout,
+	}
+}
+
+// observeTx records metrics of confirmed transaction.
+func observeTx(t *relayTx, receipt *types.Receipt) {
+	metrics.ObserveTx(t.method, time.Since(t.sentTime), receipt.GasUsed)
+	switch t.method {
+	case CCMSyncHeader:
+		metrics.AddHeaderSynced()
+	case CCMSyncStateRoot:
+		metrics.AddStateRootSynced()
+	case CCMRequestMint:
+		metrics.AddDepositMinted()
+	}
+}
+
+func (l *Relayer) updateBalance(ctx context.Context) {
+	balance, err := l.side.Eth_GetBalance(ctx, l.account.Address)
+	if err != nil {
+		log.Printf("can't get relayer balance: %s\n", err)
+		return
+	}
+	metrics.SetBalance(balance)
+}
+
+// findReceipt returns the receipt of the mined version of transaction.
+func (l *Relayer) findReceipt(ctx context.Context, hashes []common.Hash) *types.Receipt {
+	for _, h := range hashes {
+		receipt, _ := l.side.Eth_GetTransactionReceipt(ctx, h)
+		if receipt != nil {
+			return receipt
+		}
+	}
+	return nil
+}
+
+// revertReason replays reverted tx on the latest state. Native contracts
+// report failure in error while the others return revert data.
+func (l *Relayer) revertReason(ctx context.Context, tx *types.Transaction) string {
+	ret, err := l.side.Eth_Call(ctx, &sresult.TransactionObject{
+		From:     l.account.Address,
+		To:       tx.To(),
+		GasPrice: tx.GasPrice(),
+		Gas:      tx.Gas(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	})
+	if err != nil {
+		return err.Error()
+	}
+	reason, err := abi.UnpackRevert(ret)
+	if err != nil {
+		return ""
+	}
+	return reason
 }
 
 type taskBatch struct {
 	block   *block.Block
 	isJoint bool
 	tasks   []task
+	prover  *merkleProver
 }
 
 func (b taskBatch) Index() uint32 {
@@ -552,19 +1245,106 @@ func (b *taskBatch) addTask(t task) {
 	b.tasks = append(b.tasks, t)
 }
 
+// proveTx proves transaction of the block, transactions are hashed once for
+// all tasks of the batch.
+func (b *taskBatch) proveTx(txid util.Uint256) ([]byte, error) {
+	if b.prover == nil {
+		p, err := newMerkleProver(mainTxHashes(b.block))
+		if err != nil {
+			return nil, err
+		}
+		b.prover = p
+	}
+	return b.prover.Prove(common.BytesToHash(txid.BytesBE()))
+}
+
 type task interface {
 	TxId() util.Uint256
 }
 
+// taskKey identifies task in db, it's block index followed by tx hash and
+// task specific suffix.
+func taskKey(index uint32, t task) []byte {
+	key := txKey(index, t.TxId())
+	switch v := t.(type) {
+	case depositTask:
+		key = append(key, DepositPrefix)
+		key = binary.BigEndian.AppendUint64(key, v.requestId)
+	case validatorsDesignateTask:
+		key = append(key, ValidatorsKey)
+	case stateValidatorsChangeTask:
+		key = append(key, StateValidatorRole)
+		key = binary.BigEndian.AppendUint32(key, v.index)
+	}
+	return key
+}
+
+// txKey identifies main chain transaction in db, it's the prefix of keys of
+// its tasks.
+func txKey(index uint32, txid util.Uint256) []byte {
+	key := make([]byte, 4, 4+util.Uint256Size+9)
+	binary.BigEndian.PutUint32(key, index)
+	return append(key, txid.BytesBE()...)
+}
+
+func keyIndexPrefix(index uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, index)
+}
+
+// keyIndex returns the block index of task key.
+func keyIndex(key []byte) uint32 {
+	return binary.BigEndian.Uint32(key)
+}
+
+// keyTxId returns the main chain transaction of task key.
+func keyTxId(key []byte) util.Uint256 {
+	txid, _ := util.Uint256DecodeBytesBE(key[4 : 4+util.Uint256Size])
+	return txid
+}
+
+func isDepositKey(key []byte) bool {
+	return len(key) == 4+util.Uint256Size+9 && key[4+util.Uint256Size] == DepositPrefix
+}
+
+func depositKeyRequestId(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(key)-8:])
+}
+
 type depositTask struct {
 	txid      util.Uint256
 	requestId uint64
+	from      util.Uint160
+	amount    uint64
+	to        util.Uint160
 }
 
 func (t depositTask) TxId() util.Uint256 {
 	return t.txid
 }
 
+// verifyState checks deposit storage item matches the deposit event.
+func (t depositTask) verifyState(value []byte) error {
+	ds := new(depositState)
+	r := io.NewBinReaderFromBuf(value)
+	ds.DecodeBinary(r)
+	if r.Err != nil {
+		return fmt.Errorf("can't decode deposit state: %w", r.Err)
+	}
+	if ds.txid != t.txid {
+		return fmt.Errorf("txid mismatch, expect=%s, got=%s", t.txid, ds.txid)
+	}
+	if ds.from != t.from {
+		return fmt.Errorf("from mismatch, expect=%s, got=%s", t.from, ds.from)
+	}
+	if ds.amount != t.amount {
+		return fmt.Errorf("amount mismatch, expect=%d, got=%d", t.amount, ds.amount)
+	}
+	if ds.to != t.to {
+		return fmt.Errorf("to mismatch, expect=%s, got=%s", t.to, ds.to)
+	}
+	return nil
+}
+
 type validatorsDesignateTask struct {
 	txid util.Uint256
 }
@@ -0,0 +1,298 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	sresult "github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestChain persists 7 blocks: deposits in 1 and 5, validators change
+// with joint header in 3, state validators designation in 4. Only state roots
+// of even blocks are verified.
+func newTestChain(t *testing.T, l *Relayer, main *fakechain.MainChain) {
+	main.SetStateRootInterval(2)
+	sidePk, err := keys.NewSecp256k1PrivateKey()
+	require.NoError(t, err)
+	blocks := [][]fakechain.Invocation{
+		nil,
+		{
+			fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+			fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 2, Amount: MintThreshold - 1},
+		},
+		nil,
+		{fakechain.ValidatorsChange{Bridge: l.cfg.BridgeContract, Keys: keys.PublicKeys{sidePk.PublicKey()}}},
+		{fakechain.StateValidatorsDesignation{Committee: fakechain.NewCommittee(1)}},
+		{fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 3, Amount: MintThreshold}},
+		nil,
+	}
+	for i, invocations := range blocks {
+		if i == 3 {
+			main.SetNextValidators(fakechain.NewCommittee(fakechain.DefaultCommittee))
+		}
+		_, err := main.Persist(invocations...)
+		require.NoError(t, err)
+	}
+}
+
+func newTestStore(t *testing.T) *store.Store {
+	s, err := store.Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestRun(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 6
+	newTestChain(t, l, main)
+
+	require.NoError(t, l.Run(context.Background()))
+	for i := uint32(0); i < 6; i++ {
+		assert.Equal(t, i != 2, side.SyncedHeader(i) != nil, i)
+	}
+	for i := uint32(0); i < 7; i++ {
+		assert.Equal(t, i == 2 || i == 4 || i == 6, side.SyncedStateRoot(i) != nil, i)
+	}
+	assert.True(t, side.Minted(1))
+	assert.False(t, side.Minted(2))
+	assert.True(t, side.Minted(3))
+	assert.Equal(t, uint32(3), side.ValidatorsIndex())
+	assert.True(t, side.StateValidatorsSynced(5))
+	index, ok, err := l.store.LastBlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(5), index)
+}
+
+func TestRunCanceled(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	newTestChain(t, l, main)
+
+	// relayer waiting for new blocks stops once ctx is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	l.subscriber = &hookedSubscriber{MainChain: main, onSubscribe: cancel}
+	require.ErrorIs(t, l.Run(ctx), context.Canceled)
+	index, ok, err := l.store.LastBlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(6), index)
+}
+
+func TestRunResume(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 3
+	newTestChain(t, l, main)
+	require.NoError(t, l.Run(context.Background()))
+	count := len(side.Transactions())
+
+	r := newTestRelayer(t, main, side)
+	r.store = l.store
+	r.cfg.End = 6
+	require.NoError(t, r.Run(context.Background()))
+	assert.True(t, side.Minted(3))
+	// header 3, 4, 5, state root 4, 6 and 3 state syncs
+	assert.Equal(t, count+8, len(side.Transactions()))
+}
+
+func TestRunMalformedEvent(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	_, err := main.Persist()
+	require.NoError(t, err)
+	b, err := main.Persist(
+		fakechain.Notification{
+			Contract: l.cfg.BridgeContract,
+			Name:     fakechain.DepositedEventName,
+			Item:     stackitem.NewArray([]stackitem.Item{stackitem.Make(1)}),
+		},
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.True(t, side.Minted(1))
+	failed, err := l.store.FailedTasks()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(failed))
+	assert.Equal(t, txKey(1, b.Transactions[0].Hash()), failed[0].Key)
+	assert.Contains(t, failed[0].Reason, "arguments count")
+}
+
+func TestRunRejectedTask(t *testing.T) {
+	for _, reverted := range []bool{false, true} {
+		main := fakechain.NewMainChain()
+		side := fakechain.NewSideChain()
+		l := newTestRelayer(t, main, side)
+		l.store = newTestStore(t)
+		l.cfg.End = 4
+		mints := 0
+		side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+			m, err := l.bridge.Abi.MethodById(tx.Data)
+			if err == nil && m.Name == CCMRequestMint {
+				mints++
+				if mints == 2 && !reverted {
+					return 0, response.NewInvalidRequestError("Could not executing data: invalid deposited state", nil)
+				}
+			}
+			// skip execution in estimation, so the transaction is sent
+			return fakechain.DefaultGas, nil
+		})
+		_, err := main.Persist()
+		require.NoError(t, err)
+		_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+		require.NoError(t, err)
+		// deposit id reused, minting it again fails
+		b, err := main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+		require.NoError(t, err)
+		_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 2, Amount: MintThreshold})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Run(context.Background()))
+		assert.True(t, side.Minted(1))
+		assert.True(t, side.Minted(2))
+		failed, err := l.store.FailedTasks()
+		require.NoError(t, err)
+		key := taskKey(2, depositTask{txid: b.Transactions[0].Hash(), requestId: 1})
+		if reverted {
+			// reverted as minted already, so it's done
+			assert.Empty(t, failed)
+			status, err := l.store.TaskStatus(key)
+			require.NoError(t, err)
+			assert.Equal(t, store.TaskDone, status)
+			continue
+		}
+		require.Equal(t, 1, len(failed))
+		assert.Equal(t, key, failed[0].Key)
+	}
+}
+
+func TestRunTransientError(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	failures := 3
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		if failures > 0 {
+			failures--
+			return 0, errors.New("connection refused")
+		}
+		return fakechain.DefaultGas, nil
+	})
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.Equal(t, 0, failures)
+	assert.True(t, side.Minted(1))
+	failed, err := l.store.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+}
+
+func TestRunHeaderNotSynced(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	failures := 1
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		m, err := l.bridge.Abi.MethodById(tx.Data)
+		if err == nil && m.Name == CCMRequestMint && failures > 0 {
+			// node lags behind the header synced
+			failures--
+			return 0, response.NewInvalidRequestError("Could not executing data: header not found", nil)
+		}
+		return fakechain.DefaultGas, nil
+	})
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.Equal(t, 0, failures)
+	assert.True(t, side.Minted(1))
+	failed, err := l.store.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+}
+
+func TestRunShutdown(t *testing.T) {
+	for _, drained := range []bool{true, false} {
+		drained := drained
+		main := fakechain.NewMainChain()
+		side := fakechain.NewSideChain()
+		side.SetManualMining(true)
+		l := newTestRelayer(t, main, side)
+		l.store = newTestStore(t)
+		if drained {
+			l.cfg.ShutdownTimeout = 60
+		}
+		newTestChain(t, l, main)
+
+		// shutdown is requested once block 1 mint is sent, it's mined after
+		// that only if the block in progress is waited
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		t.Cleanup(func() { close(done) })
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Millisecond):
+				}
+				if ctx.Err() == nil {
+					for _, tx := range side.Transactions() {
+						m, err := l.bridge.Abi.MethodById(tx.Data())
+						if err == nil && m.Name == CCMRequestMint {
+							cancel()
+						}
+					}
+				}
+				if ctx.Err() == nil || drained {
+					side.Mine()
+				}
+			}
+		}()
+		require.ErrorIs(t, l.Run(ctx), context.Canceled)
+		index, ok, err := l.store.LastBlock()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, drained, side.Minted(1))
+		if drained {
+			assert.Equal(t, uint32(1), index)
+		} else {
+			assert.Equal(t, uint32(0), index)
+		}
+		assert.Nil(t, side.SyncedHeader(2))
+	}
+}
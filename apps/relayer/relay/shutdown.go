@@ -0,0 +1,28 @@
+package relay
+
+import (
+	"context"
+	"time"
+)
+
+// drainContext returns a context which isn't canceled with ctx but timeout
+// after it, so that transactions of the block in progress are confirmed on
+// shutdown while no new block is fetched.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	dctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-dctx.Done():
+			return
+		}
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			cancel()
+		case <-dctx.Done():
+		}
+	}()
+	return dctx, cancel
+}
@@ -0,0 +1,269 @@
+package relay
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/constantclient"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+const (
+	// MaxRecentFailures is the number of the latest failures kept in status.
+	MaxRecentFailures = 32
+	// MaxQueuedRerelays is the number of re-relays waiting for the loop,
+	// more are rejected.
+	MaxQueuedRerelays = 16
+)
+
+var (
+	ErrNotRelayed     = errors.New("block not relayed yet")
+	ErrUnknownRequest = errors.New("no task of request")
+	ErrBusy           = errors.New("too many re-relays queued")
+)
+
+// SeedReporter is implemented by clients which check health of seeds.
+type SeedReporter interface {
+	SeedStatuses() (main []constantclient.SeedStatus, side []constantclient.SeedStatus)
+}
+
+var _ SeedReporter = (*constantclient.ConstantClient)(nil)
+
+// Status is a snapshot of what the running relayer is doing.
+type Status struct {
+	// Index is the main chain block being relayed.
+	Index         uint32                      `json:"index"`
+	Best          bool                        `json:"best"`
+	Paused        bool                        `json:"paused"`
+	LastHeader    *HeaderStatus               `json:"lastHeader,omitempty"`
+	LastStateRoot *StateRootStatus            `json:"lastStateRoot,omitempty"`
+	Pending       []PendingTx                 `json:"pending"`
+	MainSeeds     []constantclient.SeedStatus `json:"mainSeeds,omitempty"`
+	SideSeeds     []constantclient.SeedStatus `json:"sideSeeds,omitempty"`
+	// Failures are the latest failures, the oldest first.
+	Failures []Failure `json:"failures"`
+}
+
+// HeaderStatus is the last main chain header relayed.
+type HeaderStatus struct {
+	Index uint32       `json:"index"`
+	Hash  util.Uint256 `json:"hash"`
+}
+
+// StateRootStatus is the last verified main chain state root relayed.
+type StateRootStatus struct {
+	Index uint32       `json:"index"`
+	Root  util.Uint256 `json:"root"`
+}
+
+// PendingTx is a side chain transaction waiting for confirmation.
+type PendingTx struct {
+	Hash   common.Hash `json:"hash"`
+	Nonce  uint64      `json:"nonce"`
+	Method string      `json:"method"`
+	// MainTx is empty for header and state root sync.
+	MainTx   *util.Uint256 `json:"mainTx,omitempty"`
+	Versions int           `json:"versions"`
+	SentAt   time.Time     `json:"sentAt"`
+}
+
+// Failure is a task failed permanently or a transient error retried, Key is
+// the hex task key of the former.
+type Failure struct {
+	Time  time.Time `json:"time"`
+	Key   string    `json:"key,omitempty"`
+	Error string    `json:"error"`
+}
+
+// rerelay is a block or a deposit request queued to be relayed again.
+type rerelay struct {
+	index uint32
+	// key is the task key of the deposit request, the whole block is
+	// relayed if it's nil.
+	key []byte
+}
+
+// Status returns the current status of the relayer with seed health.
+func (l *Relayer) Status() Status {
+	l.statusMtx.RLock()
+	s := l.status
+	s.Paused = l.paused != nil
+	s.Pending = append([]PendingTx{}, s.Pending...)
+	s.Failures = append([]Failure{}, s.Failures...)
+	l.statusMtx.RUnlock()
+	if r, ok := l.side.(SeedReporter); ok {
+		s.MainSeeds, s.SideSeeds = r.SeedStatuses()
+	}
+	return s
+}
+
+func (l *Relayer) updateStatus(update func(s *Status)) {
+	l.statusMtx.Lock()
+	defer l.statusMtx.Unlock()
+	update(&l.status)
+}
+
+func (l *Relayer) setBest() {
+	l.best = true
+	l.updateStatus(func(s *Status) { s.Best = true })
+}
+
+func (l *Relayer) setLastHeader(index uint32, hash util.Uint256) {
+	l.updateStatus(func(s *Status) { s.LastHeader = &HeaderStatus{Index: index, Hash: hash} })
+}
+
+func (l *Relayer) setLastStateRoot(index uint32, root util.Uint256) {
+	l.updateStatus(func(s *Status) { s.LastStateRoot = &StateRootStatus{Index: index, Root: root} })
+}
+
+// setPending records transactions of indexes sent and unconfirmed.
+func (l *Relayer) setPending(transactions []relayTx, indexes []int) {
+	pending := make([]PendingTx, 0, len(indexes))
+	for _, i := range indexes {
+		t := &transactions[i]
+		if len(t.hashes) == 0 {
+			continue
+		}
+		p := PendingTx{
+			Hash:     t.tx.Hash(),
+			Nonce:    t.tx.Nonce(),
+			Method:   t.method,
+			Versions: len(t.hashes),
+			SentAt:   t.sentTime,
+		}
+		if t.mainTx != (util.Uint256{}) {
+			mainTx := t.mainTx
+			p.MainTx = &mainTx
+		}
+		pending = append(pending, p)
+	}
+	l.updateStatus(func(s *Status) { s.Pending = pending })
+}
+
+// addFailure records err in recent failures, key is nil for transient errors.
+func (l *Relayer) addFailure(key []byte, err error) {
+	f := Failure{Time: time.Now(), Error: err.Error()}
+	if key != nil {
+		f.Key = hex.EncodeToString(key)
+	}
+	l.updateStatus(func(s *Status) {
+		s.Failures = append(s.Failures, f)
+		if len(s.Failures) > MaxRecentFailures {
+			s.Failures = s.Failures[len(s.Failures)-MaxRecentFailures:]
+		}
+	})
+}
+
+// Pause stops Run before the next block, the block in progress is finished.
+// Queued re-relays are served while paused.
+func (l *Relayer) Pause() {
+	l.statusMtx.Lock()
+	defer l.statusMtx.Unlock()
+	if l.paused == nil {
+		l.paused = make(chan struct{})
+		log.Printf("relayer paused\n")
+	}
+}
+
+// Resume continues Run paused.
+func (l *Relayer) Resume() {
+	l.statusMtx.Lock()
+	defer l.statusMtx.Unlock()
+	if l.paused != nil {
+		close(l.paused)
+		l.paused = nil
+		log.Printf("relayer resumed\n")
+	}
+}
+
+// RerelayBlock queues a block relayed already to be relayed again between
+// blocks, its failed tasks are tried again.
+func (l *Relayer) RerelayBlock(index uint32) error {
+	l.statusMtx.RLock()
+	next := l.status.Index
+	l.statusMtx.RUnlock()
+	if index >= next {
+		return fmt.Errorf("%w: %d", ErrNotRelayed, index)
+	}
+	return l.queueRerelay(rerelay{index: index})
+}
+
+// RerelayRequest queues the deposit of request id to be relayed again between
+// blocks, it's tried again if it failed. Deposits below threshold have no
+// task to relay.
+func (l *Relayer) RerelayRequest(id uint64) error {
+	d, err := l.store.Deposit(id)
+	if err != nil {
+		return fmt.Errorf("can't get deposit %d: %w", id, err)
+	}
+	if d == nil || d.Status == store.DepositBelowThreshold {
+		return fmt.Errorf("%w %d", ErrUnknownRequest, id)
+	}
+	key := taskKey(d.Block, depositTask{txid: d.MainTx, requestId: id})
+	return l.queueRerelay(rerelay{index: d.Block, key: key})
+}
+
+func (l *Relayer) queueRerelay(r rerelay) error {
+	select {
+	case l.rerelays <- r:
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+// serveAdmin runs queued re-relays and waits while the relayer is paused, it
+// returns ctx error once ctx is done.
+func (l *Relayer) serveAdmin(ctx context.Context) error {
+	for {
+		select {
+		case r := <-l.rerelays:
+			l.rerelay(ctx, r)
+			continue
+		default:
+		}
+		l.statusMtx.RLock()
+		paused := l.paused
+		l.statusMtx.RUnlock()
+		if paused == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-paused:
+		case r := <-l.rerelays:
+			l.rerelay(ctx, r)
+		}
+	}
+}
+
+func (l *Relayer) rerelay(ctx context.Context, r rerelay) {
+	prefix := r.key
+	if prefix == nil {
+		prefix = keyIndexPrefix(r.index)
+	}
+	err := l.store.ResetFailedTasks(prefix)
+	if err != nil {
+		log.Printf("can't reset failed tasks, key=%s: %s\n", hex.EncodeToString(prefix), err)
+		return
+	}
+	if r.key == nil {
+		log.Printf("re-relaying block %d\n", r.index)
+		err = l.RelayBlock(ctx, r.index)
+	} else {
+		log.Printf("re-relaying request %d, block %d\n", depositKeyRequestId(r.key), r.index)
+		err = l.RelayTx(ctx, keyTxId(r.key))
+	}
+	if err != nil {
+		err = fmt.Errorf("can't re-relay block %d: %w", r.index, err)
+		log.Printf("%s\n", err)
+		l.addFailure(nil, err)
+	}
+}
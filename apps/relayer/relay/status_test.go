@@ -0,0 +1,107 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response"
+	sresult "github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRerelayRequest(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		m, err := l.bridge.Abi.MethodById(tx.Data)
+		if err == nil && m.Name == CCMRequestMint {
+			return 0, response.NewInvalidRequestError("Could not executing data: invalid deposited state", nil)
+		}
+		return fakechain.DefaultGas, nil
+	})
+	_, err := main.Persist()
+	require.NoError(t, err)
+	b, err := main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+	require.NoError(t, l.Run(context.Background()))
+	require.False(t, side.Minted(1))
+	d, err := l.store.Deposit(1)
+	require.NoError(t, err)
+	assert.Equal(t, store.DepositFailed, d.Status)
+	assert.Contains(t, d.Reason, "invalid deposited state")
+
+	status := l.Status()
+	assert.Equal(t, uint32(1), status.Index)
+	require.NotNil(t, status.LastHeader)
+	assert.Equal(t, b.Hash(), status.LastHeader.Hash)
+	require.NotNil(t, status.LastStateRoot)
+	assert.Empty(t, status.Pending)
+	require.Equal(t, 1, len(status.Failures))
+	assert.Contains(t, status.Failures[0].Error, "invalid deposited state")
+
+	require.ErrorIs(t, l.RerelayBlock(1), ErrNotRelayed)
+	require.ErrorIs(t, l.RerelayRequest(2), ErrUnknownRequest)
+	require.NoError(t, l.RerelayRequest(1))
+	// the rejection is gone, e.g. the bridge is fixed
+	side.SetEstimateGas(nil)
+	require.NoError(t, l.serveAdmin(context.Background()))
+	assert.True(t, side.Minted(1))
+	failed, err := l.store.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	d, err = l.store.Deposit(1)
+	require.NoError(t, err)
+	assert.Equal(t, store.DepositMinted, d.Status)
+	assert.Empty(t, d.Reason)
+	assert.NotNil(t, d.MintTx)
+}
+
+func TestPause(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+
+	l.Pause()
+	assert.True(t, l.Status().Paused)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.serveAdmin(ctx), context.DeadlineExceeded)
+
+	done := make(chan error)
+	go func() { done <- l.serveAdmin(context.Background()) }()
+	l.Resume()
+	require.NoError(t, <-done)
+	assert.False(t, l.Status().Paused)
+}
+
+func TestRerelayDesignationBeforeStart(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 3
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.StateValidatorsDesignation{Committee: fakechain.NewCommittee(1)})
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.StateValidatorsDesignation{Committee: fakechain.NewCommittee(1)})
+	require.NoError(t, err)
+	require.NoError(t, l.Run(context.Background()))
+
+	// block 1 designation is re-relayed once relaying resumes from block 3
+	require.NoError(t, l.RerelayBlock(1))
+	l.cfg.End = 4
+	_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, l.Run(ctx))
+	assert.True(t, side.Minted(1))
+}
@@ -0,0 +1,59 @@
+package relay
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+)
+
+// notificationFilters are main chain notifications deposits and state
+// validators designations are relayed for.
+func (l *Relayer) notificationFilters() []neorpc.NotificationFilter {
+	deposited := DepositedEventName
+	designation := DesignationEventName
+	return []neorpc.NotificationFilter{
+		{Contract: &l.cfg.BridgeContract, Name: &deposited},
+		{Contract: &l.roleManagementContractAddress, Name: &designation},
+	}
+}
+
+// subscribe subscribes for main chain blocks unless subscribed already or
+// the last subscription failed or dropped less than blockTime ago. The
+// subscription lives until ctx is done, so it's made with the ctx of Run and
+// shared by all blocks.
+func (l *Relayer) subscribe(ctx context.Context) {
+	if l.subscriber == nil || l.events != nil || time.Now().Before(l.resubscribeAt) {
+		return
+	}
+	events, err := l.subscriber.Subscribe(ctx, l.notificationFilters())
+	if err != nil {
+		log.Printf("can't subscribe for main blocks, polling: %s\n", err)
+		l.resubscribeAt = time.Now().Add(l.blockTime)
+		return
+	}
+	l.events = events
+}
+
+// waitBlock waits for the next main chain block. With a subscription it
+// returns once a block or notification is pushed, blockTime still bounds
+// the wait in case events are missed. Blocks are polled every blockTime
+// while subscription is unavailable. It returns ctx error once ctx is done.
+func (l *Relayer) waitBlock(ctx context.Context) error {
+	if l.events == nil {
+		return sleep(ctx, l.blockTime)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case _, ok := <-l.events:
+		if !ok {
+			log.Printf("main subscription dropped, polling\n")
+			l.events = nil
+			l.resubscribeAt = time.Now().Add(l.blockTime)
+		}
+	case <-time.After(l.blockTime):
+	}
+	return nil
+}
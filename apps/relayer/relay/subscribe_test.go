@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSubscriber struct{}
+
+func (failingSubscriber) Subscribe(ctx context.Context, filters []neorpc.NotificationFilter) (<-chan struct{}, error) {
+	return nil, errors.New("connection refused")
+}
+
+// hookedSubscriber calls onSubscribe in background on the first
+// subscription and counts subscriptions.
+type hookedSubscriber struct {
+	*fakechain.MainChain
+	onSubscribe func()
+	once        sync.Once
+	count       int
+}
+
+func (s *hookedSubscriber) Subscribe(ctx context.Context, filters []neorpc.NotificationFilter) (<-chan struct{}, error) {
+	ch, err := s.MainChain.Subscribe(ctx, filters)
+	s.count++
+	s.once.Do(func() { go s.onSubscribe() })
+	return ch, err
+}
+
+// waitBlockIn subscribes and fails t if waitBlock doesn't return in timeout.
+func waitBlockIn(t *testing.T, l *Relayer, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		l.subscribe(context.Background())
+		l.waitBlock(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("waitBlock timed out")
+	}
+}
+
+func TestWaitBlock(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.subscriber = main
+
+	// pushed block wakes before blockTime
+	l.blockTime = time.Hour
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, err := main.Persist()
+		assert.NoError(t, err)
+	}()
+	waitBlockIn(t, l, 5*time.Second)
+	require.NotNil(t, l.events)
+
+	// dropped subscription falls back to polling
+	l.blockTime = 10 * time.Millisecond
+	main.DropSubscriptions()
+	waitBlockIn(t, l, time.Second)
+	assert.Nil(t, l.events)
+	waitBlockIn(t, l, time.Second)
+	assert.Nil(t, l.events)
+
+	// and resubscribes after blockTime
+	time.Sleep(l.blockTime)
+	waitBlockIn(t, l, time.Second)
+	assert.NotNil(t, l.events)
+
+	// waits within a block don't resubscribe with the ctx of the block
+	main.DropSubscriptions()
+	require.NoError(t, l.waitBlock(context.Background()))
+	time.Sleep(l.blockTime)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, l.waitBlock(ctx))
+	cancel()
+	assert.Nil(t, l.events)
+
+	l.subscriber = failingSubscriber{}
+	l.events = nil
+	l.resubscribeAt = time.Time{}
+	waitBlockIn(t, l, time.Second)
+	assert.Nil(t, l.events)
+}
+
+func TestRunSubscription(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	l.cfg.End = 8
+	newTestChain(t, l, main)
+
+	// deposit comes once relayer waits for new blocks
+	subscriber := &hookedSubscriber{MainChain: main, onSubscribe: func() {
+		_, err := main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 4, Amount: MintThreshold})
+		assert.NoError(t, err)
+		_, err = main.Persist()
+		assert.NoError(t, err)
+	}}
+	l.subscriber = subscriber
+	require.NoError(t, l.Run(context.Background()))
+	assert.NotNil(t, l.events)
+	// the subscription is shared by blocks
+	assert.Equal(t, 1, subscriber.count)
+	assert.True(t, side.Minted(3))
+	assert.True(t, side.Minted(4))
+}
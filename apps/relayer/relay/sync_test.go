@@ -0,0 +1,251 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	sresult "github.com/DigitalLabs-web3/neo-go-evm/pkg/rpc/response/result"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/wallet"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRelayer(t *testing.T, main *fakechain.MainChain, side *fakechain.SideChain) *Relayer {
+	acc, err := wallet.NewAccount()
+	require.NoError(t, err)
+	cfg := &config.Config{
+		BridgeContract:  util.Uint160{1},
+		PrefetchWorkers: 4,
+		PrefetchWindow:  8,
+	}
+	main.AddContract(cfg.BridgeContract, 1)
+	l, err := newRelayer(context.Background(), cfg, acc, nil, main, side)
+	require.NoError(t, err)
+	l.blockTime = time.Millisecond
+	l.backoff = newBackoff(time.Millisecond, time.Millisecond)
+	return l
+}
+
+// startMining mines pooled side transactions every millisecond.
+func startMining(t *testing.T, side *fakechain.SideChain) {
+	side.SetManualMining(true)
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				side.Mine()
+			}
+		}
+	}()
+}
+
+func newTestBatch(t *testing.T, l *Relayer, main *fakechain.MainChain) *taskBatch {
+	b, err := main.Persist(fakechain.Deposit{
+		Bridge: l.cfg.BridgeContract,
+		Id:     1,
+		Amount: MintThreshold,
+	})
+	require.NoError(t, err)
+	batch := &taskBatch{block: b, isJoint: true}
+	batch.addTask(depositTask{txid: b.Transactions[0].Hash(), requestId: 1, amount: MintThreshold})
+	return batch
+}
+
+func TestSync(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	batch := newTestBatch(t, l, main)
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+	txs := side.Transactions()
+	require.Equal(t, 3, len(txs))
+	for i, method := range []string{CCMSyncHeader, CCMSyncStateRoot, CCMRequestMint} {
+		m, err := l.bridge.Abi.MethodById(txs[i].Data())
+		require.NoError(t, err)
+		assert.Equal(t, method, m.Name)
+	}
+}
+
+func TestSyncSkipSynced(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	batch := newTestBatch(t, l, main)
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		return 0, errors.New(CCMAlreadySyncedError)
+	})
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.Equal(t, 0, len(side.Transactions()))
+}
+
+func TestSyncMissingProof(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	batch := newTestBatch(t, l, main)
+	batch.addTask(depositTask{txid: batch.block.Transactions[0].Hash(), requestId: 2})
+
+	require.Error(t, l.sync(context.Background(), batch))
+}
+
+func TestSyncReverted(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	b, err := main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: 1})
+	require.NoError(t, err)
+	batch := &taskBatch{block: b, isJoint: true}
+	batch.addTask(depositTask{txid: b.Transactions[0].Hash(), requestId: 1, amount: 1})
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		return fakechain.DefaultGas, nil
+	})
+
+	err = l.sync(context.Background(), batch)
+	var txErr *TxError
+	require.ErrorAs(t, err, &txErr)
+	assert.ErrorIs(t, err, ErrTxReverted)
+	assert.Equal(t, CCMRequestMint, txErr.Method)
+	assert.Equal(t, b.Transactions[0].Hash(), txErr.MainTx)
+	assert.Contains(t, txErr.Reason, "unreach threshold")
+}
+
+func TestSyncRevertedSynced(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.store = newTestStore(t)
+	batch := newTestBatch(t, l, main)
+	require.NoError(t, l.sync(context.Background(), batch))
+	// another relayer synced all, so the transactions revert
+	key := taskKey(batch.Index(), batch.tasks[0])
+	require.NoError(t, l.store.PutTaskStatus(key, store.TaskUnknown))
+	side.SetEstimateGas(func(tx *sresult.TransactionObject) (uint64, error) {
+		return fakechain.DefaultGas, nil
+	})
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.Equal(t, 6, len(side.Transactions()))
+	status, err := l.store.TaskStatus(key)
+	require.NoError(t, err)
+	assert.Equal(t, store.TaskDone, status)
+	failed, err := l.store.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+}
+
+func TestSyncConfirmations(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.Confirmations = 2
+	batch := newTestBatch(t, l, main)
+
+	err := l.sync(context.Background(), batch)
+	require.ErrorIs(t, err, ErrTxTimeout)
+
+	startMining(t, side)
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+}
+
+func TestSyncReplaceStuck(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.MaxGasPrice = big.NewInt(4)
+	l.cfg.GasPriceBump = 100
+	l.cfg.ReplaceAfter = 1
+	batch := newTestBatch(t, l, main)
+	side.SetMinGasPrice(big.NewInt(3))
+	startMining(t, side)
+
+	require.NoError(t, l.sync(context.Background(), batch))
+	assert.True(t, side.Minted(1))
+	var prices []int64
+	for _, tx := range side.Transactions() {
+		m, err := l.bridge.Abi.MethodById(tx.Data())
+		require.NoError(t, err)
+		if m.Name == CCMSyncHeader {
+			prices = append(prices, tx.GasPrice().Int64())
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 4}, prices)
+}
+
+func TestSyncReplaceCapped(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	l.cfg.MaxGasPrice = big.NewInt(2)
+	l.cfg.ReplaceAfter = 1
+	batch := newTestBatch(t, l, main)
+	side.SetMinGasPrice(big.NewInt(3))
+	startMining(t, side)
+
+	require.ErrorIs(t, l.sync(context.Background(), batch), ErrTxTimeout)
+	assert.Nil(t, side.SyncedHeader(0))
+}
+
+func TestRelayBlock(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	b, err := main.Persist(
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 2, Amount: MintThreshold},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, l.RelayBlock(context.Background(), b.Index))
+	assert.True(t, side.Minted(1))
+	assert.True(t, side.Minted(2))
+	assert.NotNil(t, side.SyncedHeader(b.Index))
+	assert.Equal(t, 4, len(side.Transactions()))
+}
+
+func TestRelayTx(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	b, err := main.Persist(
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 2, Amount: MintThreshold},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, l.RelayTx(context.Background(), b.Transactions[1].Hash()))
+	assert.False(t, side.Minted(1))
+	assert.True(t, side.Minted(2))
+	assert.Equal(t, 3, len(side.Transactions()))
+}
+
+func TestRelayTxNoTask(t *testing.T) {
+	main := fakechain.NewMainChain()
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main, side)
+	b, err := main.Persist(
+		fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold},
+		fakechain.Notification{Contract: util.Uint160{2}, Name: "Transfer", Item: stackitem.NewArray(nil)},
+	)
+	require.NoError(t, err)
+
+	err = l.RelayTx(context.Background(), b.Transactions[1].Hash())
+	assert.ErrorContains(t, err, "no task in tx")
+	assert.Empty(t, side.Transactions())
+	assert.False(t, side.Minted(1))
+}
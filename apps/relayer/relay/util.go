@@ -1,38 +1,29 @@
 package relay
 
 import (
-	"encoding/binary"
-
 	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
 	sstate "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
 	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/transaction"
-	"github.com/DigitalLabs-web3/neo-go-evm/pkg/crypto/hash"
 	sio "github.com/DigitalLabs-web3/neo-go-evm/pkg/io"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/nspcc-dev/neo-go/pkg/core/block"
 	"github.com/nspcc-dev/neo-go/pkg/core/state"
-	"github.com/nspcc-dev/neo-go/pkg/util"
 )
 
-func proveTx(block *block.Block, txid util.Uint256) ([]byte, error) {
+func mainTxHashes(block *block.Block) []common.Hash {
 	hashes := make([]common.Hash, len(block.Transactions))
 	for i, tx := range block.Transactions {
 		hashes[i] = common.BytesToHash(tx.Hash().BytesBE())
 	}
-	tree, err := hash.NewMerkleTree(hashes)
-	if err != nil {
-		return nil, err
-	}
-	proofs, path, err := tree.Prove(common.BytesToHash(txid.BytesBE()))
-	if err != nil {
-		return nil, err
-	}
-	proof := make([]byte, len(proofs)*common.HashLength+4)
-	binary.LittleEndian.PutUint32(proof, path)
-	for i, hash := range proofs {
-		copy(proof[4+i*common.HashLength:], hash[:])
+	return hashes
+}
+
+func sideTxHashes(block *sblock.Block) []common.Hash {
+	hashes := make([]common.Hash, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.Hash()
 	}
-	return proof, nil
+	return hashes
 }
 
 func mainHeaderToSideHeader(h *block.Header) *sblock.Header {
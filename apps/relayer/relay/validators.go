@@ -0,0 +1,109 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/native/noderoles"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/hash"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+)
+
+// validatorsRange is the state validators signing state roots from index
+// until the next range.
+type validatorsRange struct {
+	index      uint32
+	scriptHash util.Uint160
+}
+
+// stateValidators tracks StateValidator role designations parsed from main
+// chain blocks, so that state roots are checked against the designated
+// multisig before syncing.
+type stateValidators struct {
+	main MainChain
+	// ranges are sorted by index.
+	ranges []validatorsRange
+	// designations are tracked from start until known, state validators
+	// out of it are asked from main chain.
+	start uint32
+	known uint32
+}
+
+func newStateValidators(main MainChain) *stateValidators {
+	return &stateValidators{main: main}
+}
+
+// Reset drops tracked ranges, designations are tracked from index on.
+func (v *stateValidators) Reset(index uint32) {
+	v.ranges = nil
+	v.start = index
+	v.known = index
+}
+
+// Track marks designations until index all tracked.
+func (v *stateValidators) Track(index uint32) {
+	if index > v.known {
+		v.known = index
+	}
+}
+
+// Designate adds the range of state validators designated from index. Indexes
+// out of the tracked designations are ignored, e.g. on re-relay of an old
+// block, since ranges before start are not parsed.
+func (v *stateValidators) Designate(ctx context.Context, index uint32) error {
+	if index < v.start || index > v.known+1 {
+		return nil
+	}
+	scriptHash, err := v.fetch(ctx, index)
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(v.ranges), func(i int) bool {
+		return v.ranges[i].index >= index
+	})
+	if i < len(v.ranges) && v.ranges[i].index == index {
+		v.ranges[i].scriptHash = scriptHash
+		return nil
+	}
+	v.ranges = append(v.ranges, validatorsRange{})
+	copy(v.ranges[i+1:], v.ranges[i:])
+	v.ranges[i] = validatorsRange{index: index, scriptHash: scriptHash}
+	return nil
+}
+
+// ScriptHash returns the script hash state root at index must be signed with.
+func (v *stateValidators) ScriptHash(ctx context.Context, index uint32) (util.Uint160, error) {
+	if index < v.start || index > v.known {
+		return v.fetch(ctx, index)
+	}
+	if len(v.ranges) == 0 || v.ranges[0].index > v.start {
+		// designations before start are not parsed, begin with the
+		// validators at start
+		err := v.Designate(ctx, v.start)
+		if err != nil {
+			return util.Uint160{}, err
+		}
+	}
+	i := sort.Search(len(v.ranges), func(i int) bool {
+		return v.ranges[i].index > index
+	})
+	return v.ranges[i-1].scriptHash, nil
+}
+
+func (v *stateValidators) fetch(ctx context.Context, index uint32) (util.Uint160, error) {
+	pks, err := v.main.GetDesignatedByRole(ctx, noderoles.StateValidator, index)
+	if err != nil {
+		return util.Uint160{}, fmt.Errorf("can't get state validators at %d: %w", index, err)
+	}
+	if len(pks) == 0 {
+		return util.Uint160{}, errors.New("no state validators designated")
+	}
+	script, err := smartcontract.CreateDefaultMultiSigRedeemScript(pks)
+	if err != nil {
+		return util.Uint160{}, fmt.Errorf("can't create state validators script: %w", err)
+	}
+	return hash.Hash160(script), nil
+}
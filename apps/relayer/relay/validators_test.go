@@ -0,0 +1,54 @@
+package relay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateValidators(t *testing.T) {
+	main := fakechain.NewMainChain()
+	initial := main.StateValidators()
+	designated := fakechain.NewCommittee(1)
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.StateValidatorsDesignation{Committee: designated})
+	require.NoError(t, err)
+
+	v := newStateValidators(main)
+	v.Reset(1)
+	require.NoError(t, v.Designate(context.Background(), 2))
+	v.Track(2)
+	for i, expected := range []*fakechain.Committee{initial, initial, designated, designated} {
+		scriptHash, err := v.ScriptHash(context.Background(), uint32(i))
+		require.NoError(t, err)
+		assert.Equal(t, expected.ScriptHash(), scriptHash, i)
+	}
+	assert.Equal(t, []validatorsRange{
+		{index: 1, scriptHash: initial.ScriptHash()},
+		{index: 2, scriptHash: designated.ScriptHash()},
+	}, v.ranges)
+}
+
+func TestStateValidatorsDesignateUntracked(t *testing.T) {
+	main := fakechain.NewMainChain()
+	first := fakechain.NewCommittee(1)
+	second := fakechain.NewCommittee(1)
+	_, err := main.Persist(fakechain.StateValidatorsDesignation{Committee: first})
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.StateValidatorsDesignation{Committee: second})
+	require.NoError(t, err)
+
+	v := newStateValidators(main)
+	v.Reset(3)
+	v.Track(4)
+	// designation of block 0 is re-relayed
+	require.NoError(t, v.Designate(context.Background(), 1))
+	assert.Empty(t, v.ranges)
+	scriptHash, err := v.ScriptHash(context.Background(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, second.ScriptHash(), scriptHash)
+}
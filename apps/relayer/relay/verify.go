@@ -0,0 +1,98 @@
+package relay
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+
+	"github.com/nspcc-dev/neo-go/pkg/config/netmode"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/hash"
+	"github.com/nspcc-dev/neo-go/pkg/crypto/keys"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm"
+	"github.com/nspcc-dev/neo-go/pkg/vm/opcode"
+)
+
+// ErrInvalidBlock means main chain seed returns a block which can't be
+// relayed, it's retried with another seed.
+var ErrInvalidBlock = errors.New("invalid main block")
+
+// ErrInvalidStateRoot means main chain seed returns a state root which is not
+// signed by the designated state validators.
+var ErrInvalidStateRoot = errors.New("invalid state root")
+
+// verifyBlock checks b is the block following prev: transactions match
+// merkle root, PrevHash is the hash of prev and witness is signed by
+// prev.NextConsensus. prev is nil for genesis block.
+func verifyBlock(magic netmode.Magic, prev *block.Header, b *block.Block) error {
+	if b.ComputeMerkleRoot() != b.MerkleRoot {
+		return errors.New("merkle root mismatch")
+	}
+	if prev == nil {
+		return nil
+	}
+	if prev.Index+1 != b.Index {
+		return fmt.Errorf("unexpected index %d after %d", b.Index, prev.Index)
+	}
+	if b.PrevHash != prev.Hash() {
+		return fmt.Errorf("prev hash mismatch, expect=%s, got=%s", prev.Hash().StringLE(), b.PrevHash.StringLE())
+	}
+	return verifyWitness(magic, &b.Header, b.Script, prev.NextConsensus)
+}
+
+// verifyWitness checks witness of hh is a signature or multisig contract
+// with the scriptHash and signatures are valid.
+func verifyWitness(magic netmode.Magic, hh hash.Hashable, witness transaction.Witness, scriptHash util.Uint160) error {
+	if witness.ScriptHash() != scriptHash {
+		return fmt.Errorf("unexpected witness script %s, expect=%s", witness.ScriptHash().StringLE(), scriptHash.StringLE())
+	}
+	m, pks, ok := vm.ParseMultiSigContract(witness.VerificationScript)
+	if !ok {
+		pk, ok := vm.ParseSignatureContract(witness.VerificationScript)
+		if !ok {
+			return errors.New("unsupported witness script")
+		}
+		m, pks = 1, [][]byte{pk}
+	}
+	sigs, err := parseSignatures(witness.InvocationScript)
+	if err != nil {
+		return err
+	}
+	if len(sigs) < m {
+		return fmt.Errorf("not enough signatures, expect=%d, got=%d", m, len(sigs))
+	}
+	digest := hash.NetSha256(uint32(magic), hh)
+	// signatures are in the order of keys like CHECKMULTISIG requires
+	i := 0
+	for _, sig := range sigs[:m] {
+		for ; i < len(pks); i++ {
+			pk, err := keys.NewPublicKeyFromBytes(pks[i], elliptic.P256())
+			if err != nil {
+				return fmt.Errorf("invalid public key: %w", err)
+			}
+			if pk.Verify(sig, digest[:]) {
+				break
+			}
+		}
+		if i == len(pks) {
+			return errors.New("invalid signature")
+		}
+		i++
+	}
+	return nil
+}
+
+// parseSignatures reads signatures pushed by invocation script.
+func parseSignatures(script []byte) ([][]byte, error) {
+	var sigs [][]byte
+	for len(script) > 0 {
+		if len(script) < 2+keys.SignatureLen || script[0] != byte(opcode.PUSHDATA1) || script[1] != keys.SignatureLen {
+			return nil, errors.New("invalid invocation script")
+		}
+		sigs = append(sigs, script[2:2+keys.SignatureLen])
+		script = script[2+keys.SignatureLen:]
+	}
+	return sigs, nil
+}
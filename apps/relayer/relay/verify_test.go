@@ -0,0 +1,140 @@
+package relay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/nspcc-dev/neo-go/pkg/config/netmode"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBlock(t *testing.T) {
+	main := fakechain.NewMainChain()
+	main.AddContract(util.Uint160{1}, 1)
+	genesis, err := main.Persist()
+	require.NoError(t, err)
+	b, err := main.Persist(fakechain.Deposit{Bridge: util.Uint160{1}, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+
+	require.NoError(t, verifyBlock(fakechain.Magic, nil, genesis))
+	require.NoError(t, verifyBlock(fakechain.Magic, &genesis.Header, b))
+	assert.Error(t, verifyBlock(netmode.MainNet, &genesis.Header, b))
+	assert.Error(t, verifyBlock(fakechain.Magic, &b.Header, b))
+
+	tampered := *b
+	tampered.Transactions = nil
+	assert.Error(t, verifyBlock(fakechain.Magic, &genesis.Header, &tampered))
+
+	tampered = *b
+	tampered.PrevHash = util.Uint256{1}
+	assert.Error(t, verifyBlock(fakechain.Magic, &genesis.Header, &tampered))
+
+	assert.Error(t, verifyBlock(fakechain.Magic, &genesis.Header, tamper(b)))
+
+	tampered = *b
+	tampered.Script = fakechain.NewCommittee(fakechain.DefaultCommittee).Sign(uint32(fakechain.Magic), &tampered.Header)
+	assert.Error(t, verifyBlock(fakechain.Magic, &genesis.Header, &tampered))
+
+	tampered = *b
+	tampered.Script.InvocationScript = b.Script.InvocationScript[:len(b.Script.InvocationScript)-66]
+	assert.Error(t, verifyBlock(fakechain.Magic, &genesis.Header, &tampered))
+}
+
+// lyingMain returns tampered blocks until seed is switched.
+type lyingMain struct {
+	*fakechain.MainChain
+	switched int
+}
+
+func (m *lyingMain) GetBlock(ctx context.Context, index uint32) (*block.Block, error) {
+	b, err := m.MainChain.GetBlock(ctx, index)
+	if err != nil || m.switched > 0 || index == 0 {
+		return b, err
+	}
+	return tamper(b), nil
+}
+
+// tamper copies b with another timestamp, header hash isn't cached in the copy.
+func tamper(b *block.Block) *block.Block {
+	return &block.Block{
+		Header: block.Header{
+			Version:       b.Version,
+			PrevHash:      b.PrevHash,
+			MerkleRoot:    b.MerkleRoot,
+			Timestamp:     b.Timestamp + 1,
+			Nonce:         b.Nonce,
+			Index:         b.Index,
+			PrimaryIndex:  b.PrimaryIndex,
+			NextConsensus: b.NextConsensus,
+			Script:        b.Script,
+		},
+		Transactions: b.Transactions,
+	}
+}
+
+func (m *lyingMain) SwitchMainSeed(ctx context.Context) error {
+	m.switched++
+	return nil
+}
+
+func TestRunSwitchLyingSeed(t *testing.T) {
+	main := &lyingMain{MainChain: fakechain.NewMainChain()}
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main.MainChain, side)
+	l.main = main
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.Equal(t, 1, main.switched)
+	assert.True(t, side.Minted(1))
+}
+
+// forgingMain returns state roots signed by forger until seed is switched.
+type forgingMain struct {
+	*fakechain.MainChain
+	forger   *fakechain.Committee
+	switched int
+}
+
+func (m *forgingMain) GetStateRoot(ctx context.Context, index uint32) (*state.MPTRoot, error) {
+	root, err := m.MainChain.GetStateRoot(ctx, index)
+	if err != nil || m.switched > 0 || len(root.Witness) == 0 {
+		return root, err
+	}
+	forged := &state.MPTRoot{Version: root.Version, Index: root.Index, Root: root.Root}
+	forged.Witness = []transaction.Witness{m.forger.Sign(uint32(fakechain.Magic), forged)}
+	return forged, nil
+}
+
+func (m *forgingMain) SwitchMainSeed(ctx context.Context) error {
+	m.switched++
+	return nil
+}
+
+func TestRunSwitchForgingSeed(t *testing.T) {
+	main := &forgingMain{MainChain: fakechain.NewMainChain(), forger: fakechain.NewCommittee(fakechain.DefaultCommittee)}
+	side := fakechain.NewSideChain()
+	l := newTestRelayer(t, main.MainChain, side)
+	l.main = main
+	l.store = newTestStore(t)
+	l.cfg.End = 2
+	_, err := main.Persist()
+	require.NoError(t, err)
+	_, err = main.Persist(fakechain.Deposit{Bridge: l.cfg.BridgeContract, Id: 1, Amount: MintThreshold})
+	require.NoError(t, err)
+
+	require.NoError(t, l.Run(context.Background()))
+	assert.Equal(t, 1, main.switched)
+	assert.True(t, side.Minted(1))
+}
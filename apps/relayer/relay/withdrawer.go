@@ -0,0 +1,458 @@
+package relay
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/constantclient"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/metrics"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	sstate "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/actor"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/vmstate"
+	mwallet "github.com/nspcc-dev/neo-go/pkg/wallet"
+)
+
+const (
+	SideLockIdKey                = 0x05
+	SidePrefixLock               = 0x06
+	SideLockName                 = "lock"
+	BridgeSyncHeader             = "syncHeader"
+	BridgeSyncStateRoot          = "syncStateRoot"
+	BridgeWithdraw               = "withdraw"
+	BridgeAlreadyExistsError     = "already exists"
+	BridgeAlreadyWithdrawedError = "already withdrawed"
+	MainScriptFailedError        = "script failed"
+)
+
+// Withdrawer relays side chain locks to main chain bridge contract.
+type Withdrawer struct {
+	cfg           *config.Config
+	lastStateRoot *sstate.MPTRoot
+	main          MainActor
+	side          SideChain
+	store         *store.Store
+	bridge        *sstate.NativeContract
+	account       *mwallet.Account
+	best          bool
+	blockTime     time.Duration
+	backoff       *backoff
+}
+
+// NewWithdrawer creates a withdrawer of client chains, chains are initialized
+// with ctx.
+func NewWithdrawer(ctx context.Context, cfg *config.Config, acc *mwallet.Account, db *store.Store, client *constantclient.ConstantClient) (*Withdrawer, error) {
+	return newWithdrawer(ctx, cfg, acc, db, client, client)
+}
+
+func newWithdrawer(ctx context.Context, cfg *config.Config, acc *mwallet.Account, db *store.Store, main MainActor, side SideChain) (*Withdrawer, error) {
+	bridge, err := side.Eth_NativeContract(ctx, BridgeContractName)
+	if err != nil {
+		return nil, fmt.Errorf("can't get bridge contract %w", err)
+	}
+	return &Withdrawer{
+		cfg:       cfg,
+		main:      main,
+		side:      side,
+		store:     db,
+		bridge:    bridge,
+		account:   acc,
+		best:      false,
+		blockTime: BlockTimeSeconds * time.Second,
+		backoff:   newBackoff(DefaultRetryDelay, MaxRetryDelay),
+	}, nil
+}
+
+// Run relays side chain locks until cfg.SideEnd. Transient errors are retried
+// with backoff while withdraws failed with permanent errors are recorded and
+// skipped, so it returns on db errors or once ctx is done. Once ctx is done,
+// transactions of the block in progress are waited for cfg.ShutdownTimeout
+// and the block is recorded if they confirm.
+func (w *Withdrawer) Run(ctx context.Context) error {
+	start, err := w.resume()
+	if err != nil {
+		return fmt.Errorf("can't resume withdraw from db: %w", err)
+	}
+	for i := start; w.cfg.SideEnd == 0 || i < w.cfg.SideEnd; {
+		if w.best {
+			if err := sleep(ctx, w.blockTime); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		log.Printf("syncing side block, index=%d", i)
+		block, err := w.side.Eth_GetBlock(ctx, i)
+		if err != nil {
+			h, e := w.side.Eth_GetBlockCount(ctx)
+			if e != nil {
+				w.backoff.Wait(ctx, fmt.Errorf("can't get side block count: %w", e))
+				continue
+			}
+			if i >= h { // wait for the next block
+				w.best = true
+				continue
+			}
+			w.backoff.Wait(ctx, fmt.Errorf("can't get side block %d: %w", i, err))
+			continue
+		}
+		locks, err := w.findLocks(ctx, block)
+		if err != nil {
+			w.backoff.Wait(ctx, fmt.Errorf("can't find locks in side block %d: %w", i, err))
+			continue
+		}
+		if len(locks) > 0 {
+			bctx, cancel := drainContext(ctx, time.Duration(w.cfg.ShutdownTimeout)*time.Second)
+			err = w.withdrawBlock(bctx, block, locks)
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Printf("side block %d unfinished on shutdown: %s\n", i, err)
+					return ctx.Err()
+				}
+				w.backoff.Wait(ctx, fmt.Errorf("can't withdraw side block %d: %w", i, err))
+				continue
+			}
+		}
+		w.backoff.Reset()
+		err = w.store.PutSideBlock(&block.Header)
+		if err != nil {
+			return fmt.Errorf("can't persist side block %d: %w", i, err)
+		}
+		i++
+	}
+	return nil
+}
+
+func (w *Withdrawer) resume() (uint32, error) {
+	index, ok, err := w.store.LastSideBlock()
+	if err != nil {
+		return 0, err
+	}
+	stateroot, err := w.store.LastSideStateRoot()
+	if err != nil {
+		return 0, err
+	}
+	w.lastStateRoot = stateroot
+	if !ok || index+1 < w.cfg.SideStart {
+		return w.cfg.SideStart, nil
+	}
+	log.Printf("continue withdraw after stop, last synced side block=%d", index)
+	return index + 1, nil
+}
+
+type lockTask struct {
+	txid   common.Hash
+	lockId uint64
+}
+
+// findLocks returns locks of successful transactions in block. A transaction
+// can lock several times and lock through contracts, the lock event is logged
+// with the transaction sender as address. Lock ids are assigned sequentially,
+// so the ids of block locks end with the lock id counter stored at the state
+// of this block, each id is checked against the transaction of its lock state.
+func (w *Withdrawer) findLocks(ctx context.Context, block *sblock.Block) ([]lockTask, error) {
+	event, ok := w.bridge.Abi.Events[SideLockName]
+	if !ok {
+		return nil, errors.New("lock event not found in bridge abi")
+	}
+	locks := []lockTask{}
+	for _, tx := range block.Transactions {
+		receipt, err := w.side.Eth_GetTransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("can't get receipt, tx=%s: %w", tx.Hash(), err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		for _, l := range receipt.Logs {
+			if len(l.Topics) > 0 && l.Topics[0] == event.ID && l.Address == tx.From() {
+				locks = append(locks, lockTask{txid: tx.Hash()})
+			}
+		}
+	}
+	if len(locks) == 0 {
+		return locks, nil
+	}
+	stateroot, err := w.side.Eth_GetStateRoot(ctx, block.Index)
+	if err != nil {
+		return nil, fmt.Errorf("can't get state root: %w", err)
+	}
+	b, err := w.side.Eth_GetState(ctx, stateroot.Root, w.bridge.Address, []byte{SideLockIdKey})
+	if err != nil {
+		return nil, fmt.Errorf("can't get lock id: %w", err)
+	}
+	if len(b) != 8 {
+		return nil, errors.New("invalid lock id")
+	}
+	last := binary.LittleEndian.Uint64(b)
+	if last+1 < uint64(len(locks)) {
+		return nil, errors.New("lock id less than lock count")
+	}
+	first := last + 1 - uint64(len(locks))
+	for i := range locks {
+		locks[i].lockId = first + uint64(i)
+		b, err := w.side.Eth_GetState(ctx, stateroot.Root, w.bridge.Address, lockKey(locks[i].lockId))
+		if err != nil {
+			return nil, fmt.Errorf("can't get lock %d: %w", locks[i].lockId, err)
+		}
+		if len(b) < common.HashLength || common.BytesToHash(b[:common.HashLength]) != locks[i].txid {
+			return nil, fmt.Errorf("lock %d is not of tx %s", locks[i].lockId, locks[i].txid)
+		}
+		log.Printf("lock event, index=%d, tx=%s, id=%d\n", block.Index, locks[i].txid, locks[i].lockId)
+	}
+	return locks, nil
+}
+
+// lockKey is the bridge storage key of lock id.
+func lockKey(id uint64) []byte {
+	key := make([]byte, 9)
+	key[0] = SidePrefixLock
+	binary.LittleEndian.PutUint64(key[1:], id)
+	return key
+}
+
+// withdrawBlock withdraws locks of block, if it fails permanently, the
+// unfinished withdraws are recorded failed so that the following blocks can be
+// withdrawn. Withdraws failed alone are recorded by withdraw.
+func (w *Withdrawer) withdrawBlock(ctx context.Context, block *sblock.Block, locks []lockTask) error {
+	err := w.withdraw(ctx, block, locks)
+	if err != nil && IsPermanent(err) {
+		return w.failLocks(locks, err)
+	}
+	return err
+}
+
+func (w *Withdrawer) failLocks(locks []lockTask, err error) error {
+	for _, lock := range locks {
+		status, e := w.store.WithdrawStatus(lock.lockId)
+		if e != nil {
+			return e
+		}
+		if status == store.TaskDone || status == store.TaskFailed {
+			continue
+		}
+		e = w.failLock(lock.lockId, err)
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (w *Withdrawer) failLock(id uint64, err error) error {
+	log.Printf("withdraw failed, id=%d: %s\n", id, err)
+	metrics.AddTaskFailed()
+	return w.store.PutFailedWithdraw(id, err.Error())
+}
+
+func (w *Withdrawer) withdraw(ctx context.Context, block *sblock.Block, locks []lockTask) error {
+	act, err := actor.NewSimple(w.main.RPCActor(ctx), w.account)
+	if err != nil {
+		return fmt.Errorf("can't create actor: %w", err)
+	}
+	hashes := []util.Uint256{}
+	var vub uint32
+	b, err := blockHeaderToBytes(&block.Header)
+	if err != nil {
+		return fmt.Errorf("can't encode side block header: %w", err)
+	}
+	h, v, err := w.send(act, BridgeSyncHeader, BridgeAlreadyExistsError, b)
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		hashes = append(hashes, *h)
+		vub = v
+	}
+	stateroot, err := w.getVerifiedStateRoot(ctx, block.Index)
+	if err != nil {
+		return err
+	}
+	b, err = staterootToBytes(stateroot)
+	if err != nil {
+		return fmt.Errorf("can't encode side stateroot: %w", err)
+	}
+	h, v, err = w.send(act, BridgeSyncStateRoot, BridgeAlreadyExistsError, b)
+	if err != nil {
+		return err
+	}
+	if h != nil {
+		hashes = append(hashes, *h)
+		vub = v
+	}
+	results, err := w.commitTransactions(ctx, hashes, vub)
+	if err != nil {
+		return err
+	}
+	for _, err := range results {
+		if err != nil && !strings.Contains(err.Error(), BridgeAlreadyExistsError) {
+			return permanent(err)
+		}
+	}
+	hashes = hashes[:0]
+	pending := make(map[util.Uint256]uint64, len(locks))
+	var prover *merkleProver
+	for _, lock := range locks {
+		status, err := w.store.WithdrawStatus(lock.lockId)
+		if err != nil {
+			return err
+		}
+		if status == store.TaskDone {
+			log.Printf("skip done withdraw, id=%d\n", lock.lockId)
+			continue
+		}
+		if prover == nil {
+			prover, err = newMerkleProver(sideTxHashes(block))
+			if err != nil {
+				return fmt.Errorf("can't build side tx proof: %w", err)
+			}
+		}
+		txproof, err := prover.Prove(lock.txid)
+		if err != nil {
+			return fmt.Errorf("can't build side tx proof: %w", err)
+		}
+		stateproof, err := w.side.Eth_GetProof(ctx, stateroot.Root, w.bridge.Address, lockKey(lock.lockId))
+		if err != nil {
+			return fmt.Errorf("can't get side state proof %w", err)
+		}
+		txid, err := util.Uint256DecodeBytesBE(lock.txid[:])
+		if err != nil {
+			return err
+		}
+		h, v, err := w.send(act, BridgeWithdraw, BridgeAlreadyWithdrawedError, block.Index, txid, stateroot.Index, txproof, stateproof)
+		if err != nil {
+			if !IsPermanent(err) {
+				return err
+			}
+			err = w.failLock(lock.lockId, err)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if h == nil {
+			err = w.store.PutWithdrawStatus(lock.lockId, store.TaskDone)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		hashes = append(hashes, *h)
+		vub = v
+		pending[*h] = lock.lockId
+	}
+	results, err = w.commitTransactions(ctx, hashes, vub)
+	// withdraws applied are recorded even if the others expire
+	for h, e := range results {
+		id := pending[h]
+		if e != nil && !strings.Contains(e.Error(), BridgeAlreadyWithdrawedError) {
+			e = w.failLock(id, e)
+		} else {
+			e = w.store.PutWithdrawStatus(id, store.TaskDone)
+		}
+		if e != nil {
+			return e
+		}
+	}
+	return err
+}
+
+// send invokes main chain bridge contract, it returns nil hash if the
+// invocation fails with skipError which means the object is synced already.
+// Other invocation failures are permanent.
+func (w *Withdrawer) send(act *actor.Actor, method string, skipError string, params ...interface{}) (*util.Uint256, uint32, error) {
+	h, vub, err := act.SendCall(w.cfg.BridgeContract, method, params...)
+	if err != nil {
+		if strings.Contains(err.Error(), skipError) {
+			log.Printf("%s skip synced\n", method)
+			return nil, 0, nil
+		}
+		if strings.Contains(err.Error(), MainScriptFailedError) {
+			err = permanent(err)
+		}
+		return nil, 0, fmt.Errorf("can't %s: %w", method, err)
+	}
+	log.Printf("created %s main tx, txid=%s\n", method, h.StringLE())
+	return &h, vub, nil
+}
+
+func (w *Withdrawer) getVerifiedStateRoot(ctx context.Context, index uint32) (*sstate.MPTRoot, error) {
+	if w.lastStateRoot != nil && w.lastStateRoot.Index >= index {
+		return w.lastStateRoot, nil
+	}
+	stateIndex := index
+	for stateIndex < index+MaxStateRootGetRange {
+		stateroot, err := w.side.Eth_GetStateRoot(ctx, stateIndex)
+		if err != nil {
+			if w.best {
+				if err := sleep(ctx, w.blockTime); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, fmt.Errorf("can't get side state root, %w", err)
+		}
+		if len(stateroot.Witness.VerificationScript) == 0 {
+			stateIndex++
+			continue
+		}
+		log.Printf("verified side state root found, index=%d", stateIndex)
+		err = w.store.PutSideStateRoot(stateroot)
+		if err != nil {
+			return nil, fmt.Errorf("can't persist side state root: %w", err)
+		}
+		w.lastStateRoot = stateroot
+		return stateroot, nil
+	}
+	return nil, errors.New("can't get verified side state root, exceeds MaxStateRootGetRange")
+}
+
+// commitTransactions waits for main transactions of hashes to be applied. It
+// returns the results of transactions applied by hash, the error is nil if
+// the transaction halts, so each one is handled even if the others fail or
+// expire.
+func (w *Withdrawer) commitTransactions(ctx context.Context, hashes []util.Uint256, vub uint32) (map[util.Uint256]error, error) {
+	results := make(map[util.Uint256]error, len(hashes))
+	appending := hashes
+	for len(appending) > 0 {
+		err := sleep(ctx, w.blockTime)
+		if err != nil {
+			return results, err
+		}
+		rest := make([]util.Uint256, 0, len(appending))
+		for _, h := range appending {
+			applicationlog, _ := w.main.GetApplicationLog(ctx, h)
+			if applicationlog == nil {
+				rest = append(rest, h)
+				continue
+			}
+			if len(applicationlog.Executions) == 0 {
+				return results, fmt.Errorf("main tx %s has no execution", h.StringLE())
+			}
+			results[h] = nil
+			if applicationlog.Executions[0].VMState != vmstate.Halt {
+				results[h] = fmt.Errorf("main tx %s failed: %s", h.StringLE(), applicationlog.Executions[0].FaultException)
+			}
+		}
+		appending = rest
+		if len(appending) > 0 {
+			height, err := w.main.GetBlockCount(ctx)
+			if err == nil && height > vub {
+				return results, fmt.Errorf("main transactions expired: %v", appending)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,309 @@
+package relay
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DigitalLabs-web3/neo-evm-bridge/config"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/fakechain"
+	"github.com/DigitalLabs-web3/neo-evm-bridge/store"
+	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	sstate "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	"github.com/DigitalLabs-web3/neo-go-evm/pkg/core/transaction"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	mtransaction "github.com/nspcc-dev/neo-go/pkg/core/transaction"
+	"github.com/nspcc-dev/neo-go/pkg/neorpc/result"
+	"github.com/nspcc-dev/neo-go/pkg/rpcclient/actor"
+	"github.com/nspcc-dev/neo-go/pkg/smartcontract"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/nspcc-dev/neo-go/pkg/vm/stackitem"
+	"github.com/nspcc-dev/neo-go/pkg/vm/vmstate"
+	mwallet "github.com/nspcc-dev/neo-go/pkg/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultActor is main chain where bridge invocations or transactions fault.
+type faultActor struct {
+	*fakechain.MainChain
+	rpc *faultRPC
+	// faults are exceptions of transactions sent in order, the ones empty
+	// halt.
+	faults []string
+}
+
+func (a *faultActor) RPCActor(ctx context.Context) actor.RPCActor {
+	return a.rpc
+}
+
+func (a *faultActor) GetApplicationLog(ctx context.Context, txid util.Uint256) (*result.ApplicationLog, error) {
+	a.rpc.mtx.Lock()
+	defer a.rpc.mtx.Unlock()
+	for i, h := range a.rpc.sent {
+		if h != txid {
+			continue
+		}
+		execution := state.Execution{VMState: vmstate.Halt}
+		if i < len(a.faults) && a.faults[i] != "" {
+			execution = state.Execution{VMState: vmstate.Fault, FaultException: a.faults[i]}
+		}
+		return &result.ApplicationLog{Container: txid, Executions: []state.Execution{execution}}, nil
+	}
+	return nil, errors.New("unknown transaction")
+}
+
+// faultRPC faults invocations with exception after failing errs times, they
+// halt if exception is empty.
+type faultRPC struct {
+	main      *fakechain.MainChain
+	mtx       sync.Mutex
+	errs      int
+	exception string
+	calls     int
+	sent      []util.Uint256
+}
+
+func (a *faultRPC) InvokeFunction(contract util.Uint160, operation string, params []smartcontract.Parameter, signers []mtransaction.Signer) (*result.Invoke, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.calls++
+	if a.errs > 0 {
+		a.errs--
+		return nil, errors.New("connection refused")
+	}
+	if a.exception == "" {
+		return &result.Invoke{State: "HALT", Script: []byte{1}}, nil
+	}
+	return &result.Invoke{State: "FAULT", FaultException: a.exception, Script: []byte{1}}, nil
+}
+
+func (a *faultRPC) InvokeContractVerify(contract util.Uint160, params []smartcontract.Parameter, signers []mtransaction.Signer, witnesses ...mtransaction.Witness) (*result.Invoke, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *faultRPC) InvokeScript(script []byte, signers []mtransaction.Signer) (*result.Invoke, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *faultRPC) TerminateSession(sessionID uuid.UUID) (bool, error) {
+	return false, errors.New("not supported")
+}
+
+func (a *faultRPC) TraverseIterator(sessionID, iteratorID uuid.UUID, maxItemsCount int) ([]stackitem.Item, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *faultRPC) CalculateNetworkFee(tx *mtransaction.Transaction) (int64, error) {
+	return 0, nil
+}
+
+func (a *faultRPC) GetBlockCount() (uint32, error) {
+	return a.main.GetBlockCount(context.Background())
+}
+
+func (a *faultRPC) GetVersion() (*result.Version, error) {
+	return a.main.GetVersion(context.Background())
+}
+
+func (a *faultRPC) SendRawTransaction(tx *mtransaction.Transaction) (util.Uint256, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.sent = append(a.sent, tx.Hash())
+	return tx.Hash(), nil
+}
+
+// lockTx is a side chain transaction to contract logging locks events, they're
+// logged by sender like native bridge does unless emitter is set.
+type lockTx struct {
+	to       common.Address
+	locks    int
+	emitter  *common.Address
+	reverted bool
+}
+
+// addLockBlock adds a side block of txs with state where lock ids from first
+// are stored, it returns txs hashes.
+func addLockBlock(t *testing.T, side *fakechain.SideChain, first uint64, txs ...lockTx) (*sblock.Block, []common.Hash) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(fakechain.DefaultChainId))
+	event := side.Bridge().Abi.Events[SideLockName]
+	b := &sblock.Block{}
+	receipts := make([]*types.Receipt, 0, len(txs))
+	hashes := make([]common.Hash, 0, len(txs))
+	for i, lt := range txs {
+		to := lt.to
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &to, Gas: fakechain.DefaultGas, GasPrice: big.NewInt(1)}), signer, key)
+		require.NoError(t, err)
+		etx, err := transaction.NewEthTx(tx)
+		require.NoError(t, err)
+		b.Transactions = append(b.Transactions, transaction.NewTx(etx))
+		emitter := etx.Sender
+		if lt.emitter != nil {
+			emitter = *lt.emitter
+		}
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: tx.Hash()}
+		if lt.reverted {
+			receipt.Status = types.ReceiptStatusFailed
+		}
+		for j := 0; j < lt.locks; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: emitter, Topics: []common.Hash{event.ID, {}}})
+		}
+		receipts = append(receipts, receipt)
+		hashes = append(hashes, tx.Hash())
+	}
+	side.AddBlock(b, receipts...)
+	root := common.Hash{byte(b.Index + 1)}
+	side.AddStateRoot(&sstate.MPTRoot{Index: b.Index, Root: root})
+	id := first
+	for i, lt := range txs {
+		if lt.reverted || lt.emitter != nil {
+			continue
+		}
+		for j := 0; j < lt.locks; j++ {
+			side.AddState(root, side.Bridge().Address, lockKey(id), append(hashes[i].Bytes(), make([]byte, 48)...))
+			id++
+		}
+	}
+	if id > first {
+		counter := make([]byte, 8)
+		binary.LittleEndian.PutUint64(counter, id-1)
+		side.AddState(root, side.Bridge().Address, []byte{SideLockIdKey}, counter)
+	}
+	return b, hashes
+}
+
+func TestFindLocks(t *testing.T) {
+	side := fakechain.NewSideChain()
+	w := &Withdrawer{side: side, bridge: side.Bridge()}
+	bridge := side.Bridge().Address
+	contract := common.Address{0xc}
+
+	t.Run("one lock", func(t *testing.T) {
+		b, hashes := addLockBlock(t, side, 0,
+			lockTx{to: contract},
+			lockTx{to: bridge, locks: 1},
+			lockTx{to: bridge, locks: 1, reverted: true},
+		)
+		locks, err := w.findLocks(context.Background(), b)
+		require.NoError(t, err)
+		assert.Equal(t, []lockTask{{txid: hashes[1], lockId: 0}}, locks)
+	})
+	t.Run("several locks in one tx", func(t *testing.T) {
+		b, hashes := addLockBlock(t, side, 1,
+			lockTx{to: bridge, locks: 1},
+			lockTx{to: contract, locks: 2},
+		)
+		locks, err := w.findLocks(context.Background(), b)
+		require.NoError(t, err)
+		assert.Equal(t, []lockTask{
+			{txid: hashes[0], lockId: 1},
+			{txid: hashes[1], lockId: 2},
+			{txid: hashes[1], lockId: 3},
+		}, locks)
+	})
+	t.Run("lock via contract call", func(t *testing.T) {
+		b, hashes := addLockBlock(t, side, 4,
+			lockTx{to: contract, locks: 1},
+			lockTx{to: contract, locks: 1, emitter: &contract},
+		)
+		locks, err := w.findLocks(context.Background(), b)
+		require.NoError(t, err)
+		assert.Equal(t, []lockTask{{txid: hashes[0], lockId: 4}}, locks)
+	})
+	t.Run("no locks", func(t *testing.T) {
+		b, _ := addLockBlock(t, side, 5, lockTx{to: bridge})
+		locks, err := w.findLocks(context.Background(), b)
+		require.NoError(t, err)
+		assert.Empty(t, locks)
+	})
+	t.Run("lock of other tx", func(t *testing.T) {
+		b, hashes := addLockBlock(t, side, 5, lockTx{to: bridge, locks: 1})
+		root := common.Hash{byte(b.Index + 1)}
+		side.AddState(root, bridge, lockKey(5), append(common.Hash{1}.Bytes(), make([]byte, 48)...))
+		_, err := w.findLocks(context.Background(), b)
+		assert.ErrorContains(t, err, hashes[0].String())
+	})
+}
+
+func TestWithdrawerRun(t *testing.T) {
+	chain := fakechain.NewMainChain()
+	rpc := &faultRPC{main: chain, errs: 2, exception: "invalid header"}
+	main := &faultActor{MainChain: chain, rpc: rpc}
+	side := fakechain.NewSideChain()
+	acc, err := mwallet.NewAccount()
+	require.NoError(t, err)
+	db := newTestStore(t)
+	cfg := &config.Config{BridgeContract: util.Uint160{1}, SideEnd: 3}
+	w, err := newWithdrawer(context.Background(), cfg, acc, db, main, side)
+	require.NoError(t, err)
+	w.blockTime = time.Millisecond
+	w.backoff = newBackoff(time.Millisecond, time.Millisecond)
+	addLockBlock(t, side, 0)
+	addLockBlock(t, side, 0, lockTx{to: side.Bridge().Address, locks: 2})
+	addLockBlock(t, side, 2)
+
+	require.NoError(t, w.Run(context.Background()))
+	// transient errors are retried, the fault fails both withdraws of block
+	assert.Equal(t, 3, rpc.calls)
+	withdraws, err := db.FailedWithdraws()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(withdraws))
+	for i, f := range withdraws {
+		assert.Equal(t, uint64(i), f.LockId)
+		assert.Contains(t, f.Reason, "invalid header")
+	}
+	index, ok, err := db.LastSideBlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(2), index)
+	status, err := db.WithdrawStatus(1)
+	require.NoError(t, err)
+	assert.Equal(t, store.TaskFailed, status)
+}
+
+func TestWithdrawFaults(t *testing.T) {
+	chain := fakechain.NewMainChain()
+	rpc := &faultRPC{main: chain}
+	// header and state root halt, the second withdraw is done by another
+	// relayer already
+	main := &faultActor{MainChain: chain, rpc: rpc, faults: []string{"", "", "", BridgeAlreadyWithdrawedError, "invalid mpt proof"}}
+	side := fakechain.NewSideChain()
+	acc, err := mwallet.NewAccount()
+	require.NoError(t, err)
+	db := newTestStore(t)
+	cfg := &config.Config{BridgeContract: util.Uint160{1}}
+	w, err := newWithdrawer(context.Background(), cfg, acc, db, main, side)
+	require.NoError(t, err)
+	w.blockTime = time.Millisecond
+	b, _ := addLockBlock(t, side, 0, lockTx{to: side.Bridge().Address, locks: 3})
+	root := common.Hash{byte(b.Index + 1)}
+	side.AddStateRoot(&sstate.MPTRoot{Index: b.Index, Root: root, Witness: transaction.Witness{VerificationScript: []byte{1}}})
+	for id := uint64(0); id < 3; id++ {
+		side.AddProof(root, side.Bridge().Address, lockKey(id), []byte{1})
+	}
+	locks, err := w.findLocks(context.Background(), b)
+	require.NoError(t, err)
+
+	require.NoError(t, w.withdrawBlock(context.Background(), b, locks))
+	require.Equal(t, 5, len(rpc.sent))
+	for id, expected := range []store.TaskStatus{store.TaskDone, store.TaskDone, store.TaskFailed} {
+		status, err := db.WithdrawStatus(uint64(id))
+		require.NoError(t, err)
+		assert.Equal(t, expected, status, id)
+	}
+	withdraws, err := db.FailedWithdraws()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(withdraws))
+	assert.Equal(t, uint64(2), withdraws[0].LockId)
+	assert.Contains(t, withdraws[0].Reason, "invalid mpt proof")
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"go.etcd.io/bbolt"
+)
+
+type DepositStatus string
+
+const (
+	// DepositBelowThreshold deposits are not minted.
+	DepositBelowThreshold DepositStatus = "belowThreshold"
+	DepositPending        DepositStatus = "pending"
+	DepositMinted         DepositStatus = "minted"
+	// DepositFailed means the mint is given up because of permanent error.
+	DepositFailed DepositStatus = "failed"
+)
+
+var (
+	depositsBucket = []byte("deposits")
+	// senders and recipients index deposits by sender or recipient followed
+	// by request id.
+	sendersBucket    = []byte("depositSenders")
+	recipientsBucket = []byte("depositRecipients")
+)
+
+// Deposit is a deposit event seen in main chain and where its mint is.
+type Deposit struct {
+	RequestId uint64         `json:"requestId"`
+	MainTx    util.Uint256   `json:"mainTx"`
+	Block     uint32         `json:"block"`
+	From      util.Uint160   `json:"from"`
+	Amount    uint64         `json:"amount"`
+	To        common.Address `json:"to"`
+	Status    DepositStatus  `json:"status"`
+	// MintTx is the side chain transaction minting the deposit, it's empty
+	// until the mint is sent or if it's minted by another relayer.
+	MintTx *common.Hash `json:"mintTx,omitempty"`
+	// Reason is why the mint failed.
+	Reason    string    `json:"reason,omitempty"`
+	SeenAt    time.Time `json:"seenAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Deposit returns deposit of request id, nil if it's not seen.
+func (s *Store) Deposit(id uint64) (*Deposit, error) {
+	var d *Deposit
+	_, err := s.get(depositsBucket, requestKey(id), func(b []byte) error {
+		d = new(Deposit)
+		return json.Unmarshal(b, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// PutDeposit saves d and indexes it by sender and recipient.
+func (s *Store) PutDeposit(d *Deposit) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	id := requestKey(d.RequestId)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(depositsBucket).Put(id, b)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(sendersBucket).Put(append(d.From.BytesBE(), id...), nil)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(recipientsBucket).Put(append(d.To.Bytes(), id...), nil)
+	})
+}
+
+// DepositsBySender returns deposits from sender ordered by request id.
+func (s *Store) DepositsBySender(from util.Uint160) ([]Deposit, error) {
+	return s.depositsBy(sendersBucket, from.BytesBE())
+}
+
+// DepositsByRecipient returns deposits to recipient ordered by request id.
+func (s *Store) DepositsByRecipient(to common.Address) ([]Deposit, error) {
+	return s.depositsBy(recipientsBucket, to.Bytes())
+}
+
+func (s *Store) depositsBy(index []byte, prefix []byte) ([]Deposit, error) {
+	deposits := []Deposit{}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(depositsBucket)
+		c := tx.Bucket(index).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var d Deposit
+			err := json.Unmarshal(bucket.Get(k[len(prefix):]), &d)
+			if err != nil {
+				return err
+			}
+			deposits = append(deposits, d)
+		}
+		return nil
+	})
+	return deposits, err
+}
+
+func requestKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeposits(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	defer s.Close()
+	d, err := s.Deposit(1)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	alice, bob := util.Uint160{1}, util.Uint160{2}
+	for i, from := range []util.Uint160{alice, bob, alice} {
+		require.NoError(t, s.PutDeposit(&Deposit{
+			RequestId: uint64(i + 1),
+			MainTx:    util.Uint256{byte(i)},
+			Block:     uint32(i),
+			From:      from,
+			Amount:    100,
+			To:        common.Address{byte(i % 2)},
+			Status:    DepositPending,
+			SeenAt:    now,
+			UpdatedAt: now,
+		}))
+	}
+	mint := common.Hash{9}
+	d, err = s.Deposit(3)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	d.Status = DepositMinted
+	d.MintTx = &mint
+	require.NoError(t, s.PutDeposit(d))
+
+	d, err = s.Deposit(3)
+	require.NoError(t, err)
+	assert.Equal(t, util.Uint256{2}, d.MainTx)
+	assert.Equal(t, DepositMinted, d.Status)
+	assert.Equal(t, mint, *d.MintTx)
+	assert.True(t, now.Equal(d.SeenAt))
+
+	ds, err := s.DepositsBySender(alice)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(ds))
+	assert.Equal(t, uint64(1), ds[0].RequestId)
+	assert.Equal(t, uint64(3), ds[1].RequestId)
+	assert.Equal(t, DepositMinted, ds[1].Status)
+	ds, err = s.DepositsByRecipient(common.Address{1})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(ds))
+	assert.Equal(t, uint64(2), ds[0].RequestId)
+	ds, err = s.DepositsBySender(util.Uint160{3})
+	require.NoError(t, err)
+	assert.Empty(t, ds)
+}
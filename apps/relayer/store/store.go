@@ -0,0 +1,344 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	sblock "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/block"
+	sstate "github.com/DigitalLabs-web3/neo-go-evm/pkg/core/state"
+	sio "github.com/DigitalLabs-web3/neo-go-evm/pkg/io"
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	mio "github.com/nspcc-dev/neo-go/pkg/io"
+	"go.etcd.io/bbolt"
+)
+
+type TaskStatus byte
+
+const (
+	TaskUnknown TaskStatus = iota
+	TaskPending
+	TaskDone
+	// TaskFailed means the task is given up because of permanent error.
+	TaskFailed
+)
+
+// FailedTask is a task given up, Key is the task key.
+type FailedTask struct {
+	Key    []byte
+	Reason string
+}
+
+// FailedWithdraw is a withdraw of side chain lock given up.
+type FailedWithdraw struct {
+	LockId uint64
+	Reason string
+}
+
+var (
+	checkpointBucket      = []byte("checkpoint")
+	tasksBucket           = []byte("tasks")
+	withdrawsBucket       = []byte("withdraws")
+	failedBucket          = []byte("failed")
+	failedWithdrawsBucket = []byte("failedWithdraws")
+
+	lastBlockKey         = []byte("lastBlock")
+	lastHeaderKey        = []byte("lastHeader")
+	lastStateRootKey     = []byte("lastStateRoot")
+	lastSideBlockKey     = []byte("lastSideBlock")
+	lastSideHeaderKey    = []byte("lastSideHeader")
+	lastSideStateRootKey = []byte("lastSideStateRoot")
+)
+
+// Store persists relay progress so that the relayer can continue after stop.
+type Store struct {
+	db *bbolt.DB
+}
+
+func Open(path string) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("can't open db %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{checkpointBucket, tasksBucket, withdrawsBucket, failedBucket, failedWithdrawsBucket, depositsBucket, sendersBucket, recipientsBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't initialize db: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// LastBlock returns the last main chain block which is fully synced.
+func (s *Store) LastBlock() (uint32, bool, error) {
+	return s.getIndex(lastBlockKey)
+}
+
+// LastHeader returns the header of the last fully synced block.
+func (s *Store) LastHeader() (*block.Header, error) {
+	header := new(block.Header)
+	found, err := s.get(checkpointBucket, lastHeaderKey, func(b []byte) error {
+		r := mio.NewBinReaderFromBuf(b)
+		header.DecodeBinary(r)
+		return r.Err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return header, nil
+}
+
+// PutBlock saves header as the last fully synced block.
+func (s *Store) PutBlock(header *block.Header) error {
+	b, err := toBytes(header)
+	if err != nil {
+		return err
+	}
+	return s.putIndex(lastBlockKey, header.Index, lastHeaderKey, b)
+}
+
+// LastStateRoot returns the last verified state root synced to side chain.
+func (s *Store) LastStateRoot() (*state.MPTRoot, error) {
+	root := new(state.MPTRoot)
+	found, err := s.get(checkpointBucket, lastStateRootKey, func(b []byte) error {
+		r := mio.NewBinReaderFromBuf(b)
+		root.DecodeBinary(r)
+		return r.Err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (s *Store) PutStateRoot(root *state.MPTRoot) error {
+	b, err := toBytes(root)
+	if err != nil {
+		return err
+	}
+	return s.put(checkpointBucket, lastStateRootKey, b)
+}
+
+// LastSideBlock returns the last side chain block which is fully withdrawn.
+func (s *Store) LastSideBlock() (uint32, bool, error) {
+	return s.getIndex(lastSideBlockKey)
+}
+
+// LastSideHeader returns the header of the last fully withdrawn side chain block.
+func (s *Store) LastSideHeader() (*sblock.Header, error) {
+	header := new(sblock.Header)
+	found, err := s.get(checkpointBucket, lastSideHeaderKey, func(b []byte) error {
+		return sio.FromByteArray(header, b)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return header, nil
+}
+
+// PutSideBlock saves header as the last fully withdrawn side chain block.
+func (s *Store) PutSideBlock(header *sblock.Header) error {
+	b, err := sio.ToByteArray(header)
+	if err != nil {
+		return err
+	}
+	return s.putIndex(lastSideBlockKey, header.Index, lastSideHeaderKey, b)
+}
+
+// LastSideStateRoot returns the last verified side chain state root synced to main chain.
+func (s *Store) LastSideStateRoot() (*sstate.MPTRoot, error) {
+	root := new(sstate.MPTRoot)
+	found, err := s.get(checkpointBucket, lastSideStateRootKey, func(b []byte) error {
+		return sio.FromByteArray(root, b)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (s *Store) PutSideStateRoot(root *sstate.MPTRoot) error {
+	b, err := sio.ToByteArray(root)
+	if err != nil {
+		return err
+	}
+	return s.put(checkpointBucket, lastSideStateRootKey, b)
+}
+
+func (s *Store) TaskStatus(key []byte) (TaskStatus, error) {
+	return s.getStatus(tasksBucket, key)
+}
+
+func (s *Store) PutTaskStatus(key []byte, status TaskStatus) error {
+	return s.put(tasksBucket, key, []byte{byte(status)})
+}
+
+// PutFailedTask marks task failed and records the reason.
+func (s *Store) PutFailedTask(key []byte, reason string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(tasksBucket).Put(key, []byte{byte(TaskFailed)})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(failedBucket).Put(key, []byte(reason))
+	})
+}
+
+// FailedTasks returns all failed tasks ordered by key.
+func (s *Store) FailedTasks() ([]FailedTask, error) {
+	var tasks []FailedTask
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(failedBucket).ForEach(func(k, v []byte) error {
+			tasks = append(tasks, FailedTask{
+				Key:    append([]byte{}, k...),
+				Reason: string(v),
+			})
+			return nil
+		})
+	})
+	return tasks, err
+}
+
+// ResetFailedTasks drops failure records of tasks with key prefix, so that
+// they are tried again.
+func (s *Store) ResetFailedTasks(prefix []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		failed := tx.Bucket(failedBucket)
+		var keys [][]byte
+		c := failed.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		for _, k := range keys {
+			err := failed.Delete(k)
+			if err != nil {
+				return err
+			}
+			err = tx.Bucket(tasksBucket).Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WithdrawStatus returns the status of withdraw of side chain lock.
+func (s *Store) WithdrawStatus(lockId uint64) (TaskStatus, error) {
+	return s.getStatus(withdrawsBucket, lockKey(lockId))
+}
+
+func (s *Store) PutWithdrawStatus(lockId uint64, status TaskStatus) error {
+	return s.put(withdrawsBucket, lockKey(lockId), []byte{byte(status)})
+}
+
+// PutFailedWithdraw marks withdraw of lock failed and records the reason.
+func (s *Store) PutFailedWithdraw(lockId uint64, reason string) error {
+	key := lockKey(lockId)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(withdrawsBucket).Put(key, []byte{byte(TaskFailed)})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(failedWithdrawsBucket).Put(key, []byte(reason))
+	})
+}
+
+// FailedWithdraws returns all failed withdraws ordered by lock id.
+func (s *Store) FailedWithdraws() ([]FailedWithdraw, error) {
+	var withdraws []FailedWithdraw
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(failedWithdrawsBucket).ForEach(func(k, v []byte) error {
+			withdraws = append(withdraws, FailedWithdraw{
+				LockId: binary.BigEndian.Uint64(k),
+				Reason: string(v),
+			})
+			return nil
+		})
+	})
+	return withdraws, err
+}
+
+func lockKey(lockId uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, lockId)
+	return key
+}
+
+func (s *Store) getStatus(bucket []byte, key []byte) (TaskStatus, error) {
+	status := TaskUnknown
+	_, err := s.get(bucket, key, func(b []byte) error {
+		if len(b) > 0 {
+			status = TaskStatus(b[0])
+		}
+		return nil
+	})
+	return status, err
+}
+
+func (s *Store) getIndex(key []byte) (index uint32, ok bool, err error) {
+	ok, err = s.get(checkpointBucket, key, func(b []byte) error {
+		if len(b) != 4 {
+			return errors.New("invalid index")
+		}
+		index = binary.BigEndian.Uint32(b)
+		return nil
+	})
+	return
+}
+
+// putIndex saves block index together with its header atomically.
+func (s *Store) putIndex(indexKey []byte, index uint32, headerKey []byte, header []byte) error {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, index)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(checkpointBucket)
+		err := bucket.Put(indexKey, b)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(headerKey, header)
+	})
+}
+
+func (s *Store) get(bucket []byte, key []byte, decode func([]byte) error) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get(key)
+		if v == nil {
+			return nil
+		}
+		found = true
+		return decode(v)
+	})
+	return found, err
+}
+
+func (s *Store) put(bucket []byte, key []byte, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, value)
+	})
+}
+
+func toBytes(item mio.Serializable) ([]byte, error) {
+	w := mio.NewBufBinWriter()
+	item.EncodeBinary(w.BinWriter)
+	if w.Err != nil {
+		return nil, w.Err
+	}
+	return w.Bytes(), nil
+}
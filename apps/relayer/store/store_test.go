@@ -0,0 +1,116 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nspcc-dev/neo-go/pkg/core/block"
+	"github.com/nspcc-dev/neo-go/pkg/core/state"
+	"github.com/nspcc-dev/neo-go/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relayer.db")
+	s, err := Open(path)
+	require.NoError(t, err)
+	_, ok, err := s.LastBlock()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	header := &block.Header{
+		Index:         10,
+		NextConsensus: util.Uint160{1, 2, 3},
+	}
+	require.NoError(t, s.PutBlock(header))
+	root := &state.MPTRoot{Index: 12, Root: util.Uint256{4, 5, 6}}
+	require.NoError(t, s.PutStateRoot(root))
+	require.NoError(t, s.PutTaskStatus([]byte{1}, TaskDone))
+	require.NoError(t, s.Close())
+
+	s, err = Open(path)
+	require.NoError(t, err)
+	defer s.Close()
+	index, ok, err := s.LastBlock()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(10), index)
+	h, err := s.LastHeader()
+	require.NoError(t, err)
+	assert.Equal(t, header.NextConsensus, h.NextConsensus)
+	assert.Equal(t, header.Hash(), h.Hash())
+	r, err := s.LastStateRoot()
+	require.NoError(t, err)
+	assert.Equal(t, root.Index, r.Index)
+	assert.Equal(t, root.Root, r.Root)
+	status, err := s.TaskStatus([]byte{1})
+	require.NoError(t, err)
+	assert.Equal(t, TaskDone, status)
+	status, err = s.TaskStatus([]byte{2})
+	require.NoError(t, err)
+	assert.Equal(t, TaskUnknown, status)
+}
+
+func TestFailedTasks(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	defer s.Close()
+	tasks, err := s.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	require.NoError(t, s.PutFailedTask([]byte{2}, "reverted"))
+	require.NoError(t, s.PutFailedTask([]byte{1}, "malformed event"))
+	status, err := s.TaskStatus([]byte{2})
+	require.NoError(t, err)
+	assert.Equal(t, TaskFailed, status)
+	tasks, err = s.FailedTasks()
+	require.NoError(t, err)
+	assert.Equal(t, []FailedTask{
+		{Key: []byte{1}, Reason: "malformed event"},
+		{Key: []byte{2}, Reason: "reverted"},
+	}, tasks)
+}
+
+func TestFailedWithdraws(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.PutFailedWithdraw(300, "invalid proof"))
+	require.NoError(t, s.PutFailedWithdraw(2, "main tx failed"))
+	status, err := s.WithdrawStatus(300)
+	require.NoError(t, err)
+	assert.Equal(t, TaskFailed, status)
+	withdraws, err := s.FailedWithdraws()
+	require.NoError(t, err)
+	assert.Equal(t, []FailedWithdraw{
+		{LockId: 2, Reason: "main tx failed"},
+		{LockId: 300, Reason: "invalid proof"},
+	}, withdraws)
+	tasks, err := s.FailedTasks()
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestResetFailedTasks(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "relayer.db"))
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.PutFailedTask([]byte{1, 1}, "reverted"))
+	require.NoError(t, s.PutFailedTask([]byte{1, 2}, "reverted"))
+	require.NoError(t, s.PutFailedTask([]byte{2, 1}, "reverted"))
+	require.NoError(t, s.PutTaskStatus([]byte{1, 3}, TaskDone))
+
+	require.NoError(t, s.ResetFailedTasks([]byte{1}))
+	tasks, err := s.FailedTasks()
+	require.NoError(t, err)
+	assert.Equal(t, []FailedTask{{Key: []byte{2, 1}, Reason: "reverted"}}, tasks)
+	status, err := s.TaskStatus([]byte{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, TaskUnknown, status)
+	status, err = s.TaskStatus([]byte{1, 3})
+	require.NoError(t, err)
+	assert.Equal(t, TaskDone, status)
+}